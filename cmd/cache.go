@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"mangadex-cli/internal/api"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var cacheClearExpired bool
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the API response cache",
+	Long: `Commands for inspecting and clearing the MangaDex API response cache.
+Manga details, searches, chapter details and the follow list are cached on
+disk so update checks do not fetch them on every run. Expired entries are
+revalidated with a conditional request when the server supports it.`,
+}
+
+// cacheStatsCmd represents the cache stats command
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show cache statistics",
+	Long:  `Show the number, size and age of cached responses by endpoint type.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache := newResponseCache()
+
+		stats, err := cache.Stats()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Cache Directory: %s\n", cache.Dir)
+		if cfg.HTTPCache.Disabled {
+			fmt.Println("The cache is disabled")
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Type", "TTL", "Entries", "Expired", "Size", "Oldest", "Newest"})
+
+		byType := make(map[string]api.CacheStats)
+		for _, s := range stats {
+			byType[s.Type] = s
+		}
+
+		total := api.CacheStats{}
+		for _, endpointType := range api.EndpointTypes {
+			s, ok := byType[endpointType]
+			ttl := cache.TTL(endpointType)
+			if !ok && ttl == 0 {
+				continue
+			}
+
+			ttlText := "not cached"
+			if ttl > 0 {
+				ttlText = ttl.String()
+			}
+			oldest, newest := "-", "-"
+			if s.Entries > 0 {
+				oldest = s.Oldest.Format("2006-01-02 15:04")
+				newest = s.Newest.Format("2006-01-02 15:04")
+			}
+
+			table.Append([]string{
+				endpointType,
+				ttlText,
+				strconv.Itoa(s.Entries),
+				strconv.Itoa(s.Expired),
+				formatBytes(s.Bytes),
+				oldest,
+				newest,
+			})
+
+			total.Entries += s.Entries
+			total.Expired += s.Expired
+			total.Bytes += s.Bytes
+		}
+
+		table.Render()
+		fmt.Printf("%d entries (%d expired), %s\n", total.Entries, total.Expired, formatBytes(total.Bytes))
+		return nil
+	},
+}
+
+// cacheClearCmd represents the cache clear command
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Clear the cache",
+	Long:  `Remove every cached response, or only expired ones with --expired.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		removed, err := newResponseCache().Clear(cacheClearExpired)
+		if err != nil {
+			return fmt.Errorf("failed to clear cache: %w", err)
+		}
+
+		fmt.Printf("Removed %d cached response(s)\n", removed)
+		return nil
+	},
+}
+
+// formatBytes formats a size in bytes for display
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+
+	// Add flags for clear command
+	cacheClearCmd.Flags().BoolVar(&cacheClearExpired, "expired", false, "Only remove expired entries")
+}
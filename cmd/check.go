@@ -1,15 +1,16 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"time"
+	"os"
+	"os/signal"
+	"strings"
 
 	"mangadex-cli/internal/api"
-	"mangadex-cli/internal/email"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
-	"os"
 )
 
 // checkCmd represents the check command
@@ -20,163 +21,115 @@ var checkCmd = &cobra.Command{
 This command performs the same check that the service would do on schedule.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Initialize API client
-		client := api.NewMangaDexClient(cfg.MangaDexAPIURL)
+		client := newAPIClient()
 		
-		// Set auth token if available
-		if cfg.AuthToken != "" {
-			client.SessionToken = cfg.AuthToken
-			client.RefreshToken = cfg.RefreshToken
-			client.TokenExpiry = cfg.TokenExpiry 
+		// Run the update engine
+		engine, err := newUpdateEngine(client)
+		if err != nil {
+			return err
 		}
 		
-		// Initialize email service
-		emailService := email.NewEmailService(cfg.SMTPSettings)
+		// Ctrl+C cancels the check, leaving unsent notifications queued
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
 		
-		// Get active subscriptions
-		subscriptions, err := database.ListActiveSubscriptions()
+		result, err := engine.RunContext(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to get subscriptions: %w", err)
+			return err
 		}
 		
-		if len(subscriptions) == 0 {
+		if len(result.Subscriptions) == 0 {
 			fmt.Println("No active subscriptions found")
 			return nil
 		}
 		
-		fmt.Printf("Checking updates for %d subscriptions...\n", len(subscriptions))
-		
-		// Track new chapters
-		type UpdateInfo struct {
-			MangaTitle string
-			ChapterIDs []string
-			Chapters   []api.Chapter
+		fmt.Printf("Checked %d subscriptions\n", len(result.Subscriptions))
+		if result.APICallsSaved > 0 {
+			fmt.Printf("Saved %d chapter fetch(es) by sharing them between subscriptions (%d API call(s) made)\n", result.APICallsSaved, result.APICalls)
 		}
 		
-		updates := make(map[int]*UpdateInfo) // UserID -> UpdateInfo
+		for _, err := range result.Errors {
+			fmt.Printf("Warning: %v\n", err)
+		}
 		
-		// Check each subscription for updates
-		for _, sub := range subscriptions {
-			fmt.Printf("Checking \"%s\"...\n", sub.MangaTitle)
-			
-			// Get new chapters since last check
-			chapters, err := client.GetMangaChapters(sub.MangaID, sub.LastCheckTime)
-			if err != nil {
-				fmt.Printf("Error checking \"%s\": %v\n", sub.MangaTitle, err)
-				continue
-			}
-			
-			// Filter chapters by language
-			languages := sub.GetLanguages()
-			filteredChapters := make([]api.Chapter, 0)
-			
-			for _, chapter := range chapters {
-				for _, lang := range languages {
-					if chapter.TranslatedLanguage == lang {
-						filteredChapters = append(filteredChapters, chapter)
-						break
-					}
+		// Report per-subscription outcome
+		for _, sub := range result.Subscriptions {
+			switch {
+			case sub.Err != nil:
+				fmt.Printf("Error checking \"%s\": %v\n", sub.Subscription.MangaTitle, sub.Err)
+			case len(sub.Chapters) == 0:
+				fmt.Printf("No new chapters for \"%s\"\n", sub.Subscription.MangaTitle)
+			default:
+				source := ""
+				if sub.ViaFeed {
+					source = " (follow feed)"
 				}
+				fmt.Printf("Found %d new chapter(s) for \"%s\"%s\n", len(sub.Chapters), sub.Subscription.MangaTitle, source)
 			}
-			
-			// Update last check time
-			sub.LastCheckTime = time.Now()
-			if err := database.UpdateSubscription(&sub); err != nil {
-				fmt.Printf("Error updating subscription check time: %v\n", err)
-			}
-			
-			// If no new chapters, continue
-			if len(filteredChapters) == 0 {
-				fmt.Printf("No new chapters for \"%s\"\n", sub.MangaTitle)
-				continue
+			if sub.Truncated {
+				fmt.Printf("Chapter limit reached for \"%s\", remaining chapters will be fetched next run\n", sub.Subscription.MangaTitle)
 			}
-			
-			// Get manga details
-			/*
-			manga, err := client.GetManga(sub.MangaID)
-			if err != nil {
-				fmt.Printf("Error getting manga details for \"%s\": %v\n", sub.MangaTitle, err)
-				continue
+			if sub.Held > 0 {
+				fmt.Printf("Waiting for other releases of %d chapter(s) of \"%s\"\n", sub.Held, sub.Subscription.MangaTitle)
 			}
-			*/
-			
-			// Group updates by user
-			if _, ok := updates[sub.UserID]; !ok {
-				updates[sub.UserID] = &UpdateInfo{
-					MangaTitle: sub.MangaTitle,
-					ChapterIDs: make([]string, 0),
-					Chapters:   make([]api.Chapter, 0),
-				}
-			}
-			
-			// Add chapters to user updates
-			updateInfo := updates[sub.UserID]
-			for _, chapter := range filteredChapters {
-				updateInfo.ChapterIDs = append(updateInfo.ChapterIDs, chapter.ID)
-				updateInfo.Chapters = append(updateInfo.Chapters, chapter)
-			}
-			
-			fmt.Printf("Found %d new chapter(s) for \"%s\"\n", len(filteredChapters), sub.MangaTitle)
 		}
 		
-		// Send email notifications for updates
-		for userID, updateInfo := range updates {
-			// Get user
-			user, err := database.GetUser(userID)
-			if err != nil {
-				fmt.Printf("Error getting user with ID %d: %v\n", userID, err)
-				continue
-			}
-			
-			// Get manga
-			manga, err := client.GetManga(updateInfo.MangaTitle)
-			if err != nil {
-				fmt.Printf("Error getting manga details: %v\n", err)
-				continue
-			}
-			
-			// Connect to email server
-			if err := emailService.Connect(); err != nil {
-				fmt.Printf("Error connecting to email server: %v\n", err)
-				continue
-			}
-			
-			// Send notification
-			if err := emailService.SendNotification(user.Email, manga, updateInfo.Chapters); err != nil {
-				fmt.Printf("Error sending notification to %s: %v\n", user.Email, err)
-			} else {
-				fmt.Printf("Notification sent to %s about %d new chapter(s) for \"%s\"\n", 
-					user.Email, len(updateInfo.Chapters), updateInfo.MangaTitle)
-			}
-			
-			// Disconnect from email server
-			emailService.Disconnect()
+		for _, d := range result.Deferred {
+			fmt.Printf("Holding %d chapter(s) for %s until their digest at %s\n",
+				d.Chapters, d.Email, d.DueAt.Format("2006-01-02 15:04"))
 		}
 		
 		// Display summary
-		if len(updates) == 0 {
+		if len(result.Notifications) == 0 && result.Queued == 0 {
 			fmt.Println("No updates found for any subscriptions")
-		} else {
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetHeader([]string{"User Email", "Manga", "New Chapters"})
-			
-			for userID, updateInfo := range updates {
-				user, _ := database.GetUser(userID)
-				row := []string{
-					user.Email,
-					updateInfo.MangaTitle,
-					fmt.Sprintf("%d", len(updateInfo.Chapters)),
-				}
-				table.Append(row)
+			return nil
+		}
+		
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"User Email", "Channel", "Manga", "New Chapters", "Status"})
+		
+		for _, n := range result.Notifications {
+			status := "Sent"
+			if n.Digest {
+				status = "Sent (digest)"
 			}
-			
-			fmt.Println("\nUpdate Summary:")
-			table.Render()
+			switch {
+			case n.Interrupted:
+				status = "Interrupted, will retry"
+			case n.Err != nil && n.RetryAt.IsZero():
+				status = fmt.Sprintf("Failed after %d attempt(s): %v", n.Attempt, n.Err)
+			case n.Err != nil:
+				status = fmt.Sprintf("Failed, retrying at %s: %v", n.RetryAt.Format("15:04"), n.Err)
+			}
+			row := []string{
+				n.Email,
+				n.Channel,
+				n.MangaTitle,
+				describeChapters(n.Chapters),
+				status,
+			}
+			table.Append(row)
 		}
 		
+		fmt.Println("\nUpdate Summary:")
+		table.Render()
+		
+		fmt.Printf("%d new chapter(s), %d notification(s) queued, %d sent, %d error(s)\n",
+			result.ChaptersFound(), result.Queued, result.NotificationsSent(), result.ErrorCount())
+		
 		return nil
 	},
 }
 
+// describeChapters summarizes chapters by volume, e.g. "3 (Vol. 3: Ch. 21–23)"
+func describeChapters(chapters []api.Chapter) string {
+	labels := make([]string, 0)
+	for _, volume := range api.GroupByVolume(chapters) {
+		labels = append(labels, volume.Label())
+	}
+	return fmt.Sprintf("%d (%s)", len(chapters), strings.Join(labels, "; "))
+}
+
 func init() {
 	// No specific flags for check command
 }
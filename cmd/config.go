@@ -2,8 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"net/url"
+	"strconv"
 	"strings"
 
+	"mangadex-cli/internal/api"
 	"mangadex-cli/internal/email"
 
 	"github.com/spf13/cobra"
@@ -30,6 +33,21 @@ If no setting is specified, all configuration values are shown.`,
 			fmt.Printf("Database Path: %s\n", cfg.DatabasePath)
 			fmt.Printf("MangaDex API URL: %s\n", cfg.MangaDexAPIURL)
 			fmt.Printf("Update Check Interval: %d seconds\n", cfg.UpdateCheckInterval)
+			fmt.Printf("Max Chapters Per Check: %d\n", cfg.MaxChaptersPerCheck)
+			fmt.Printf("Check Workers: %d\n", cfg.CheckWorkers)
+			fmt.Printf("API Rate Limit: %g requests/second\n", cfg.APIRateLimit)
+			fmt.Printf("API Max Retries: %d\n", cfg.APIMaxRetries)
+			fmt.Printf("Use Follow Feed: %t\n", cfg.UseFollowFeed)
+			fmt.Printf("Content Ratings: %s\n", strings.Join(defaultContentRatings(), ","))
+			fmt.Printf("Template Directory: %s\n", cfg.TemplateDir)
+			fmt.Printf("Cover Cache Directory: %s\n", coverCacheDir())
+			fmt.Printf("HTTP Cache: %t\n", !cfg.HTTPCache.Disabled)
+			fmt.Printf("HTTP Cache Directory: %s\n", newResponseCache().Dir)
+			fmt.Printf("HTTP Cache TTL: %s\n", httpCacheTTLs())
+			fmt.Printf("Description Length: %d\n", cfg.DescriptionLength)
+			fmt.Printf("Outbox Max Attempts: %d\n", cfg.OutboxMaxAttempts)
+			fmt.Printf("Unsubscribe URL: %s\n", cfg.Unsubscribe.BaseURL)
+			fmt.Printf("Unsubscribe Listen Address: %s\n", cfg.Unsubscribe.ListenAddr)
 			
 			// Show auth status but not the actual tokens
 			if cfg.AuthToken != "" {
@@ -39,12 +57,22 @@ If no setting is specified, all configuration values are shown.`,
 			}
 			
 			// Show email config without password
+			fmt.Printf("Mail Transport: %s\n", mailTransport())
+			fmt.Printf("Sendmail Path: %s\n", cfg.Mail.SendmailPath)
+			fmt.Printf("Mail Path: %s\n", cfg.Mail.Path)
 			fmt.Printf("SMTP Server: %s\n", cfg.SMTPSettings.Server)
 			fmt.Printf("SMTP Port: %d\n", cfg.SMTPSettings.Port)
 			fmt.Printf("SMTP Username: %s\n", cfg.SMTPSettings.Username)
 			fmt.Printf("SMTP Use TLS: %t\n", cfg.SMTPSettings.UseTLS)
+			fmt.Printf("SMTP Security: %s\n", email.SecurityMode(cfg.SMTPSettings))
+			fmt.Printf("SMTP Auth Mechanism: %s\n", smtpAuthMechanism())
+			fmt.Printf("SMTP CA File: %s\n", cfg.SMTPSettings.CAFile)
+			fmt.Printf("SMTP Skip Verify: %t\n", cfg.SMTPSettings.InsecureSkipVerify)
+			fmt.Printf("SMTP OAuth: %s\n", describeSMTPOAuth())
 			fmt.Printf("SMTP From Email: %s\n", cfg.SMTPSettings.FromEmail)
 			fmt.Printf("SMTP From Name: %s\n", cfg.SMTPSettings.FromName)
+			fmt.Printf("SMTP Max Messages Per Connection: %d\n", cfg.SMTPSettings.MaxMessagesPerConnection)
+			fmt.Printf("SMTP Send Rate: %g messages/second\n", cfg.SMTPSettings.SendRate)
 			
 			return nil
 		}
@@ -58,6 +86,42 @@ If no setting is specified, all configuration values are shown.`,
 			fmt.Printf("MangaDex API URL: %s\n", cfg.MangaDexAPIURL)
 		case "updatecheckinterval":
 			fmt.Printf("Update Check Interval: %d seconds\n", cfg.UpdateCheckInterval)
+		case "maxchapterspercheck":
+			fmt.Printf("Max Chapters Per Check: %d\n", cfg.MaxChaptersPerCheck)
+		case "checkworkers":
+			fmt.Printf("Check Workers: %d\n", cfg.CheckWorkers)
+		case "apiratelimit":
+			fmt.Printf("API Rate Limit: %g requests/second\n", cfg.APIRateLimit)
+		case "apimaxretries":
+			fmt.Printf("API Max Retries: %d\n", cfg.APIMaxRetries)
+		case "usefollowfeed":
+			fmt.Printf("Use Follow Feed: %t\n", cfg.UseFollowFeed)
+		case "contentratings":
+			fmt.Printf("Content Ratings: %s\n", strings.Join(defaultContentRatings(), ","))
+		case "templatedir":
+			fmt.Printf("Template Directory: %s\n", cfg.TemplateDir)
+		case "covercachedir":
+			fmt.Printf("Cover Cache Directory: %s\n", coverCacheDir())
+		case "httpcache":
+			fmt.Printf("HTTP Cache: %t\n", !cfg.HTTPCache.Disabled)
+		case "httpcachedir":
+			fmt.Printf("HTTP Cache Directory: %s\n", newResponseCache().Dir)
+		case "httpcachettl":
+			fmt.Printf("HTTP Cache TTL: %s\n", httpCacheTTLs())
+		case "descriptionlength":
+			fmt.Printf("Description Length: %d\n", cfg.DescriptionLength)
+		case "outboxmaxattempts":
+			fmt.Printf("Outbox Max Attempts: %d\n", cfg.OutboxMaxAttempts)
+		case "unsubscribeurl":
+			fmt.Printf("Unsubscribe URL: %s\n", cfg.Unsubscribe.BaseURL)
+		case "unsubscribelisten":
+			fmt.Printf("Unsubscribe Listen Address: %s\n", cfg.Unsubscribe.ListenAddr)
+		case "mailtransport":
+			fmt.Printf("Mail Transport: %s\n", mailTransport())
+		case "sendmailpath":
+			fmt.Printf("Sendmail Path: %s\n", cfg.Mail.SendmailPath)
+		case "mailpath":
+			fmt.Printf("Mail Path: %s\n", cfg.Mail.Path)
 		case "smtpserver":
 			fmt.Printf("SMTP Server: %s\n", cfg.SMTPSettings.Server)
 		case "smtpport":
@@ -66,10 +130,22 @@ If no setting is specified, all configuration values are shown.`,
 			fmt.Printf("SMTP Username: %s\n", cfg.SMTPSettings.Username)
 		case "smtpusetls":
 			fmt.Printf("SMTP Use TLS: %t\n", cfg.SMTPSettings.UseTLS)
+		case "smtpsecurity":
+			fmt.Printf("SMTP Security: %s\n", email.SecurityMode(cfg.SMTPSettings))
+		case "smtpauth":
+			fmt.Printf("SMTP Auth Mechanism: %s\n", smtpAuthMechanism())
+		case "smtpcafile":
+			fmt.Printf("SMTP CA File: %s\n", cfg.SMTPSettings.CAFile)
+		case "smtpskipverify":
+			fmt.Printf("SMTP Skip Verify: %t\n", cfg.SMTPSettings.InsecureSkipVerify)
 		case "smtpfromemail":
 			fmt.Printf("SMTP From Email: %s\n", cfg.SMTPSettings.FromEmail)
 		case "smtpfromname":
 			fmt.Printf("SMTP From Name: %s\n", cfg.SMTPSettings.FromName)
+		case "smtpmaxmessages":
+			fmt.Printf("SMTP Max Messages Per Connection: %d\n", cfg.SMTPSettings.MaxMessagesPerConnection)
+		case "smtpsendrate":
+			fmt.Printf("SMTP Send Rate: %g messages/second\n", cfg.SMTPSettings.SendRate)
 		default:
 			return fmt.Errorf("unknown setting: %s", setting)
 		}
@@ -102,6 +178,127 @@ var setCmd = &cobra.Command{
 			}
 			cfg.UpdateCheckInterval = interval
 			fmt.Printf("Update Check Interval set to: %d seconds\n", interval)
+		case "maxchapterspercheck":
+			var maxChapters int
+			if _, err := fmt.Sscanf(value, "%d", &maxChapters); err != nil {
+				return fmt.Errorf("invalid chapter limit, must be a number: %w", err)
+			}
+			cfg.MaxChaptersPerCheck = maxChapters
+			fmt.Printf("Max Chapters Per Check set to: %d\n", maxChapters)
+		case "apiratelimit":
+			var rate float64
+			if _, err := fmt.Sscanf(value, "%g", &rate); err != nil {
+				return fmt.Errorf("invalid rate limit, must be a number: %w", err)
+			}
+			cfg.APIRateLimit = rate
+			fmt.Printf("API Rate Limit set to: %g requests/second\n", rate)
+		case "apimaxretries":
+			var retries int
+			if _, err := fmt.Sscanf(value, "%d", &retries); err != nil {
+				return fmt.Errorf("invalid retry count, must be a number: %w", err)
+			}
+			cfg.APIMaxRetries = retries
+			fmt.Printf("API Max Retries set to: %d\n", retries)
+		case "usefollowfeed":
+			var useFeed bool
+			if strings.ToLower(value) == "true" {
+				useFeed = true
+			} else if strings.ToLower(value) == "false" {
+				useFeed = false
+			} else {
+				return fmt.Errorf("invalid follow feed setting, must be true or false")
+			}
+			cfg.UseFollowFeed = useFeed
+			fmt.Printf("Use Follow Feed set to: %t\n", useFeed)
+		case "contentratings":
+			ratings, err := parseContentRatings(value)
+			if err != nil {
+				return err
+			}
+			cfg.ContentRatings = ratings
+			fmt.Printf("Content Ratings set to: %s\n", strings.Join(ratings, ","))
+		case "templatedir":
+			cfg.TemplateDir = value
+			fmt.Printf("Template Directory set to: %s\n", value)
+		case "covercachedir":
+			cfg.CoverCacheDir = value
+			fmt.Printf("Cover Cache Directory set to: %s\n", coverCacheDir())
+		case "httpcache":
+			var enabled bool
+			if strings.ToLower(value) == "true" {
+				enabled = true
+			} else if strings.ToLower(value) == "false" {
+				enabled = false
+			} else {
+				return fmt.Errorf("invalid HTTP cache setting, must be true or false")
+			}
+			cfg.HTTPCache.Disabled = !enabled
+			fmt.Printf("HTTP Cache set to: %t\n", enabled)
+		case "httpcachedir":
+			cfg.HTTPCache.Dir = value
+			fmt.Printf("HTTP Cache Directory set to: %s\n", newResponseCache().Dir)
+		case "httpcachettl":
+			ttls, err := parseHTTPCacheTTLs(value)
+			if err != nil {
+				return err
+			}
+			if cfg.HTTPCache.TTL == nil {
+				cfg.HTTPCache.TTL = make(map[string]int)
+			}
+			for endpointType, seconds := range ttls {
+				cfg.HTTPCache.TTL[endpointType] = seconds
+			}
+			fmt.Printf("HTTP Cache TTL set to: %s\n", httpCacheTTLs())
+		case "descriptionlength":
+			var length int
+			if _, err := fmt.Sscanf(value, "%d", &length); err != nil {
+				return fmt.Errorf("invalid description length, must be a number: %w", err)
+			}
+			cfg.DescriptionLength = length
+			fmt.Printf("Description Length set to: %d\n", length)
+		case "checkworkers":
+			var workers int
+			if _, err := fmt.Sscanf(value, "%d", &workers); err != nil || workers < 1 {
+				return fmt.Errorf("invalid check workers, must be a positive number")
+			}
+			cfg.CheckWorkers = workers
+			fmt.Printf("Check Workers set to: %d\n", workers)
+		case "outboxmaxattempts":
+			var attempts int
+			if _, err := fmt.Sscanf(value, "%d", &attempts); err != nil || attempts < 1 {
+				return fmt.Errorf("invalid outbox max attempts, must be a positive number")
+			}
+			cfg.OutboxMaxAttempts = attempts
+			fmt.Printf("Outbox Max Attempts set to: %d\n", attempts)
+		case "unsubscribeurl":
+			if value != "" {
+				if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+					return fmt.Errorf("invalid unsubscribe URL, must be an absolute URL such as https://notifier.example.com/unsubscribe")
+				}
+			}
+			cfg.Unsubscribe.BaseURL = value
+			fmt.Printf("Unsubscribe URL set to: %s\n", value)
+		case "unsubscribelisten":
+			cfg.Unsubscribe.ListenAddr = value
+			fmt.Printf("Unsubscribe Listen Address set to: %s\n", value)
+		case "mailtransport":
+			transport := strings.ToLower(value)
+			switch transport {
+			case email.TransportSMTP, email.TransportSendmail, email.TransportMaildir, email.TransportMbox:
+			default:
+				return fmt.Errorf("invalid mail transport, must be smtp, sendmail, maildir or mbox")
+			}
+			cfg.Mail.Transport = transport
+			fmt.Printf("Mail Transport set to: %s\n", transport)
+			if (transport == email.TransportMaildir || transport == email.TransportMbox) && cfg.Mail.Path == "" {
+				fmt.Println("Note: set mailpath to the Maildir directory or mbox file")
+			}
+		case "sendmailpath":
+			cfg.Mail.SendmailPath = value
+			fmt.Printf("Sendmail Path set to: %s\n", value)
+		case "mailpath":
+			cfg.Mail.Path = value
+			fmt.Printf("Mail Path set to: %s\n", value)
 		case "smtpserver":
 			cfg.SMTPSettings.Server = value
 			fmt.Printf("SMTP Server set to: %s\n", value)
@@ -129,12 +326,71 @@ var setCmd = &cobra.Command{
 			}
 			cfg.SMTPSettings.UseTLS = useTLS
 			fmt.Printf("SMTP Use TLS set to: %t\n", useTLS)
+		case "smtpsecurity":
+			security := strings.ToLower(value)
+			switch security {
+			case email.SecuritySSL, email.SecuritySTARTTLS, email.SecurityNone:
+			default:
+				return fmt.Errorf("invalid security mode, must be ssl, starttls or none")
+			}
+			settings := cfg.SMTPSettings
+			settings.Security = security
+			if err := email.CheckCredentialSecurity(settings); err != nil {
+				return err
+			}
+			cfg.SMTPSettings.Security = security
+			fmt.Printf("SMTP Security set to: %s\n", security)
+			if security == email.SecuritySSL && cfg.SMTPSettings.Port == 587 {
+				fmt.Println("Note: port 587 usually expects starttls; implicit TLS is usually on port 465")
+			}
+			if security == email.SecuritySTARTTLS && cfg.SMTPSettings.Port == 465 {
+				fmt.Println("Note: port 465 usually expects ssl; STARTTLS is usually on port 587")
+			}
+		case "smtpauth":
+			mechanism := strings.ToLower(value)
+			switch mechanism {
+			case "auto":
+				mechanism = ""
+			case email.AuthPlain, email.AuthLogin, email.AuthCRAMMD5, email.AuthXOAUTH2:
+			default:
+				return fmt.Errorf("invalid auth mechanism, must be auto, plain, login, cram-md5 or xoauth2")
+			}
+			cfg.SMTPSettings.AuthMechanism = mechanism
+			fmt.Printf("SMTP Auth Mechanism set to: %s\n", smtpAuthMechanism())
+		case "smtpcafile":
+			cfg.SMTPSettings.CAFile = value
+			fmt.Printf("SMTP CA File set to: %s\n", value)
+		case "smtpskipverify":
+			var skipVerify bool
+			if strings.ToLower(value) == "true" {
+				skipVerify = true
+			} else if strings.ToLower(value) == "false" {
+				skipVerify = false
+			} else {
+				return fmt.Errorf("invalid skip verify setting, must be true or false")
+			}
+			cfg.SMTPSettings.InsecureSkipVerify = skipVerify
+			fmt.Printf("SMTP Skip Verify set to: %t\n", skipVerify)
 		case "smtpfromemail":
 			cfg.SMTPSettings.FromEmail = value
 			fmt.Printf("SMTP From Email set to: %s\n", value)
 		case "smtpfromname":
 			cfg.SMTPSettings.FromName = value
 			fmt.Printf("SMTP From Name set to: %s\n", value)
+		case "smtpmaxmessages":
+			var max int
+			if _, err := fmt.Sscanf(value, "%d", &max); err != nil || max < 0 {
+				return fmt.Errorf("invalid max messages per connection, must be a number of 0 or more")
+			}
+			cfg.SMTPSettings.MaxMessagesPerConnection = max
+			fmt.Printf("SMTP Max Messages Per Connection set to: %d\n", max)
+		case "smtpsendrate":
+			var rate float64
+			if _, err := fmt.Sscanf(value, "%g", &rate); err != nil || rate < 0 {
+				return fmt.Errorf("invalid send rate, must be a number of 0 or more")
+			}
+			cfg.SMTPSettings.SendRate = rate
+			fmt.Printf("SMTP Send Rate set to: %g messages/second\n", rate)
 		default:
 			return fmt.Errorf("unknown setting: %s", setting)
 		}
@@ -148,24 +404,41 @@ var setCmd = &cobra.Command{
 	},
 }
 
+var templateFormat string
+
 // testCmd represents the test command
 var testCmd = &cobra.Command{
-	Use:   "test email [recipient]",
+	Use:   "test [email|template] [recipient|kind]",
 	Short: "Test configuration",
-	Long:  `Test configuration components like email sending.`,
-	Args:  cobra.ExactArgs(2),
+	Long: `Test configuration components like email sending.
+
+  test email [recipient]   send a test email
+  test template [kind]     render a template with sample data to stdout;
+                           kind is notification (default), digest or test`,
+	Args:  cobra.RangeArgs(1, 2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		component := strings.ToLower(args[0])
-		value := args[1]
+		value := ""
+		if len(args) > 1 {
+			value = args[1]
+		}
 		
 		switch component {
 		case "email":
+			if value == "" {
+				return fmt.Errorf("recipient must be provided")
+			}
+			
 			// Test email configuration
-			emailService := email.NewEmailService(cfg.SMTPSettings)
+			emailService, err := newEmailService()
+			if err != nil {
+				return err
+			}
 			if err := emailService.Connect(); err != nil {
 				return fmt.Errorf("failed to connect to email server: %w", err)
 			}
 			defer emailService.Disconnect()
+			fmt.Printf("Connected: %s\n", emailService.ConnectionInfo())
 			
 			if err := emailService.SendTestEmail(value); err != nil {
 				return fmt.Errorf("failed to send test email: %w", err)
@@ -174,14 +447,101 @@ var testCmd = &cobra.Command{
 			fmt.Printf("Test email sent to %s\n", value)
 			return nil
 			
+		case "template":
+			if value == "" {
+				value = email.TemplateNotification
+			}
+			
+			emailService, err := newEmailService()
+			if err != nil {
+				return err
+			}
+			
+			html, text, err := emailService.RenderSample(strings.ToLower(value))
+			if err != nil {
+				return err
+			}
+			
+			switch strings.ToLower(templateFormat) {
+			case "html":
+				fmt.Print(html)
+			case "text":
+				fmt.Print(text)
+			default:
+				return fmt.Errorf("unknown format: %s", templateFormat)
+			}
+			return nil
+			
 		default:
 			return fmt.Errorf("unknown test component: %s", component)
 		}
 	},
 }
 
+// mailTransport returns the configured mail transport for display
+func mailTransport() string {
+	if cfg.Mail.Transport == "" {
+		return email.TransportSMTP
+	}
+	return cfg.Mail.Transport
+}
+
+// smtpAuthMechanism returns the configured SMTP auth mechanism for display
+func smtpAuthMechanism() string {
+	if cfg.SMTPSettings.AuthMechanism == "" {
+		return "auto"
+	}
+	return cfg.SMTPSettings.AuthMechanism
+}
+
+// describeSMTPOAuth summarizes the XOAUTH2 setup without showing secrets
+func describeSMTPOAuth() string {
+	settings := cfg.SMTPSettings.OAuth
+	switch {
+	case settings.ClientID == "":
+		return "Not configured"
+	case settings.RefreshToken != "":
+		return fmt.Sprintf("%s (signed in)", settings.Provider)
+	case settings.ClientSecret != "":
+		return fmt.Sprintf("%s (client credentials)", settings.Provider)
+	default:
+		return fmt.Sprintf("%s (not signed in)", settings.Provider)
+	}
+}
+
+// httpCacheTTLs returns the effective cache TTLs for display, in seconds
+func httpCacheTTLs() string {
+	ttls := newResponseCache().TTLs
+	
+	parts := make([]string, 0, len(api.EndpointTypes))
+	for _, endpointType := range api.EndpointTypes {
+		parts = append(parts, fmt.Sprintf("%s=%d", endpointType, int(ttls[endpointType].Seconds())))
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseHTTPCacheTTLs parses a comma separated list of type=seconds pairs
+func parseHTTPCacheTTLs(value string) (map[string]int, error) {
+	ttls := make(map[string]int)
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 || !containsString(api.EndpointTypes, parts[0]) {
+			return nil, fmt.Errorf("invalid cache TTL %q, must be type=seconds with type one of %s", pair, strings.Join(api.EndpointTypes, ", "))
+		}
+		seconds, err := strconv.Atoi(parts[1])
+		if err != nil || seconds < 0 {
+			return nil, fmt.Errorf("invalid cache TTL %q, seconds must be a number of at least 0", pair)
+		}
+		ttls[parts[0]] = seconds
+	}
+	return ttls, nil
+}
+
 func init() {
 	configCmd.AddCommand(getCmd)
 	configCmd.AddCommand(setCmd)
 	configCmd.AddCommand(testCmd)
-}
\ No newline at end of file
+	
+	// Add flags for test command
+	testCmd.Flags().StringVar(&templateFormat, "format", "html", "Template output format when testing templates (html, text)")
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"mangadex-cli/internal/config"
+	"mangadex-cli/internal/db"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	notifierType   string
+	notifierURL    string
+	notifierSecret string
+)
+
+// notifierCmd represents the notifier command
+var notifierCmd = &cobra.Command{
+	Use:   "notifier",
+	Short: "Manage notification channels",
+	Long: `Commands for adding, removing, and listing notification channels.
+The "email" channel is built in. Other channels post to Discord, Slack or
+generic JSON webhooks and can be picked per user with 'user channels'.`,
+}
+
+// notifierAddCmd represents the notifier add command
+var notifierAddCmd = &cobra.Command{
+	Use:   "add [name]",
+	Short: "Add a notification channel",
+	Long: `Add a named notification channel.
+Supported types are discord, slack and webhook. Generic webhooks can be signed
+with an HMAC secret.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if name == db.DefaultChannel {
+			return fmt.Errorf("channel name %q is reserved", name)
+		}
+		
+		notifierType = strings.ToLower(notifierType)
+		switch notifierType {
+		case "discord", "slack", "webhook":
+		default:
+			return fmt.Errorf("unknown channel type: %s", notifierType)
+		}
+		
+		if cfg.Notifiers == nil {
+			cfg.Notifiers = make(map[string]config.NotifierConfig)
+		}
+		cfg.Notifiers[name] = config.NotifierConfig{
+			Type:       notifierType,
+			WebhookURL: notifierURL,
+			Secret:     notifierSecret,
+		}
+		
+		// Save config
+		if err := cfg.Save(cfgFile); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+		
+		fmt.Printf("Notification channel \"%s\" (%s) saved\n", name, notifierType)
+		return nil
+	},
+}
+
+// notifierRemoveCmd represents the notifier remove command
+var notifierRemoveCmd = &cobra.Command{
+	Use:   "remove [name]",
+	Short: "Remove a notification channel",
+	Long: `Remove a named notification channel.
+Channels still picked by a user cannot be removed; change their channels with
+'user channels' first.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if _, ok := cfg.Notifiers[name]; !ok {
+			return fmt.Errorf("unknown notification channel: %s", name)
+		}
+		
+		// Queued notifications for users of the channel could never be delivered
+		users, err := database.ListUsers()
+		if err != nil {
+			return fmt.Errorf("failed to get users: %w", err)
+		}
+		inUse := make([]string, 0)
+		for _, user := range users {
+			for _, channel := range user.GetChannels() {
+				if channel == name {
+					inUse = append(inUse, user.Email)
+					break
+				}
+			}
+		}
+		if len(inUse) > 0 {
+			return fmt.Errorf("notification channel \"%s\" is used by %s; change their channels with 'user channels' first",
+				name, strings.Join(inUse, ", "))
+		}
+		
+		delete(cfg.Notifiers, name)
+		
+		// Save config
+		if err := cfg.Save(cfgFile); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+		
+		fmt.Printf("Notification channel \"%s\" removed\n", name)
+		return nil
+	},
+}
+
+// notifierListCmd represents the notifier list command
+var notifierListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List notification channels",
+	Long:  `List all notification channels.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names := make([]string, 0, len(cfg.Notifiers))
+		for name := range cfg.Notifiers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Name", "Type", "Webhook URL", "Signed"})
+		table.Append([]string{db.DefaultChannel, "email", cfg.SMTPSettings.Server, "-"})
+		
+		for _, name := range names {
+			settings := cfg.Notifiers[name]
+			signed := "No"
+			if settings.Secret != "" {
+				signed = "Yes"
+			}
+			table.Append([]string{name, settings.Type, maskWebhookURL(settings.WebhookURL), signed})
+		}
+		table.Render()
+		
+		return nil
+	},
+}
+
+// maskWebhookURL hides the secret part of a webhook URL. Discord and Slack
+// webhook URLs end in a token that grants posting to the channel, so the last
+// path segment and any query are masked.
+func maskWebhookURL(webhookURL string) string {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || parsed.Host == "" {
+		return "********"
+	}
+	
+	masked := parsed.Scheme + "://" + parsed.Host
+	path := strings.TrimSuffix(parsed.Path, "/")
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		masked += path[:i+1] + "********"
+	}
+	if parsed.RawQuery != "" {
+		masked += "?********"
+	}
+	return masked
+}
+
+func init() {
+	configCmd.AddCommand(notifierCmd)
+	notifierCmd.AddCommand(notifierAddCmd)
+	notifierCmd.AddCommand(notifierRemoveCmd)
+	notifierCmd.AddCommand(notifierListCmd)
+	
+	// Add flags for add command
+	notifierAddCmd.Flags().StringVarP(&notifierType, "type", "t", "", "Channel type (discord, slack, webhook)")
+	notifierAddCmd.Flags().StringVarP(&notifierURL, "url", "u", "", "Webhook URL")
+	notifierAddCmd.Flags().StringVarP(&notifierSecret, "secret", "s", "", "HMAC signing secret (webhook type only)")
+	notifierAddCmd.MarkFlagRequired("type")
+	notifierAddCmd.MarkFlagRequired("url")
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"mangadex-cli/internal/db"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	outboxStatus      string
+	outboxPurgeStatus string
+	outboxOlderThan   time.Duration
+)
+
+// outboxCmd represents the outbox command
+var outboxCmd = &cobra.Command{
+	Use:   "outbox",
+	Short: "Manage queued notifications",
+	Long: `Commands for inspecting and managing the notification outbox.
+Every notification is queued before it is sent. Failed notifications are
+retried with exponential backoff and given up after the configured number of
+attempts; given up notifications can be retried or purged from here.`,
+}
+
+// outboxListCmd represents the outbox list command
+var outboxListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List queued notifications",
+	Long:  `List queued, sent and failed notifications, optionally filtered by status.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutboxStatus(outboxStatus, true); err != nil {
+			return err
+		}
+
+		items, err := database.ListOutboxItems(outboxStatus)
+		if err != nil {
+			return fmt.Errorf("failed to list outbox: %w", err)
+		}
+
+		if len(items) == 0 {
+			fmt.Println("No notifications found")
+			return nil
+		}
+
+		// Cache user emails
+		emails := make(map[int]string)
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"ID", "User Email", "Channel", "Content", "Chapters", "Status", "Attempts", "Next Attempt", "Last Error"})
+
+		for _, item := range items {
+			email, ok := emails[item.UserID]
+			if !ok {
+				email = fmt.Sprintf("user %d", item.UserID)
+				if user, err := database.GetUser(item.UserID); err == nil {
+					email = user.Email
+				}
+				emails[item.UserID] = email
+			}
+
+			content := item.Summary
+			if item.Digest && item.MangaID == "" {
+				content = "Digest: " + content
+			}
+
+			next := "-"
+			switch {
+			case item.Status == db.OutboxPending:
+				next = item.NextAttemptAt.Format("2006-01-02 15:04")
+			case item.SentAt != nil:
+				next = "Sent " + item.SentAt.Format("2006-01-02 15:04")
+			}
+
+			table.Append([]string{
+				strconv.Itoa(item.ID),
+				email,
+				item.Channel,
+				content,
+				strconv.Itoa(len(item.GetChapterIDs())),
+				item.Status,
+				strconv.Itoa(item.Attempts),
+				next,
+				item.LastError,
+			})
+		}
+		table.Render()
+
+		return nil
+	},
+}
+
+// outboxRetryCmd represents the outbox retry command
+var outboxRetryCmd = &cobra.Command{
+	Use:   "retry [id...]",
+	Short: "Retry failed notifications",
+	Long: `Queue failed notifications for delivery again with a fresh set of attempts.
+If no IDs are given, every failed notification is retried. They are sent on
+the next check or by the running service.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ids := make([]int, 0, len(args))
+		for _, arg := range args {
+			id, err := strconv.Atoi(arg)
+			if err != nil {
+				return fmt.Errorf("invalid outbox ID %q: %w", arg, err)
+			}
+			ids = append(ids, id)
+		}
+
+		count, err := database.RetryOutboxItems(ids)
+		if err != nil {
+			return fmt.Errorf("failed to retry notifications: %w", err)
+		}
+
+		fmt.Printf("%d failed notification(s) queued for retry\n", count)
+		return nil
+	},
+}
+
+// outboxPurgeCmd represents the outbox purge command
+var outboxPurgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Delete sent or failed notifications",
+	Long: `Delete sent or failed notifications from the outbox.
+Purging failed notifications gives up on their chapters for good.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutboxStatus(outboxPurgeStatus, false); err != nil {
+			return err
+		}
+		if outboxPurgeStatus == db.OutboxPending {
+			return fmt.Errorf("pending notifications cannot be purged")
+		}
+
+		count, err := database.PurgeOutboxItems(outboxPurgeStatus, time.Now().Add(-outboxOlderThan))
+		if err != nil {
+			return fmt.Errorf("failed to purge notifications: %w", err)
+		}
+
+		fmt.Printf("%d %s notification(s) purged\n", count, outboxPurgeStatus)
+		return nil
+	},
+}
+
+// validateOutboxStatus checks an outbox status flag
+func validateOutboxStatus(status string, allowEmpty bool) error {
+	switch status {
+	case db.OutboxPending, db.OutboxSent, db.OutboxFailed:
+		return nil
+	case "":
+		if allowEmpty {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid status %q, must be pending, sent or failed", status)
+}
+
+func init() {
+	outboxCmd.AddCommand(outboxListCmd)
+	outboxCmd.AddCommand(outboxRetryCmd)
+	outboxCmd.AddCommand(outboxPurgeCmd)
+
+	// Add flags for list command
+	outboxListCmd.Flags().StringVarP(&outboxStatus, "status", "s", "", "Only list notifications with a status (pending, sent, failed)")
+
+	// Add flags for purge command
+	outboxPurgeCmd.Flags().StringVarP(&outboxPurgeStatus, "status", "s", db.OutboxSent, "Status of the notifications to purge (sent, failed)")
+	outboxPurgeCmd.Flags().DurationVar(&outboxOlderThan, "older-than", 0, "Only purge notifications last updated before this long ago, e.g. 168h")
+}
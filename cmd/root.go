@@ -4,9 +4,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
+	"mangadex-cli/internal/api"
 	"mangadex-cli/internal/config"
 	"mangadex-cli/internal/db"
+	"mangadex-cli/internal/email"
+	"mangadex-cli/internal/notify"
+	"mangadex-cli/internal/unsubscribe"
+	"mangadex-cli/internal/updater"
 
 	"github.com/spf13/cobra"
 )
@@ -78,6 +84,149 @@ configure email settings, and test the notification service through this CLI.`,
 	},
 }
 
+// newAPIClient creates a MangaDex API client from the loaded configuration
+func newAPIClient() *api.MangaDexClient {
+	client := api.NewMangaDexClient(cfg.MangaDexAPIURL)
+	
+	if cfg.MaxChaptersPerCheck > 0 {
+		client.MaxChapters = cfg.MaxChaptersPerCheck
+	}
+	if cfg.APIRateLimit > 0 {
+		client.SetRateLimit(cfg.APIRateLimit)
+	}
+	if cfg.APIMaxRetries > 0 {
+		client.MaxRetries = cfg.APIMaxRetries
+	}
+	if !cfg.HTTPCache.Disabled {
+		client.Cache = newResponseCache()
+	}
+	
+	// Set auth token if available
+	if cfg.AuthToken != "" {
+		client.SessionToken = cfg.AuthToken
+		client.RefreshToken = cfg.RefreshToken
+		client.TokenExpiry = cfg.TokenExpiry
+	}
+	
+	return client
+}
+
+// newResponseCache creates the API response cache from the loaded
+// configuration, by default in an "http-cache" directory next to the database
+func newResponseCache() *api.ResponseCache {
+	dir := cfg.HTTPCache.Dir
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(cfg.DatabasePath), "http-cache")
+	}
+	
+	cache := api.NewResponseCache(dir)
+	for endpointType, seconds := range cfg.HTTPCache.TTL {
+		cache.TTLs[endpointType] = time.Duration(seconds) * time.Second
+	}
+	return cache
+}
+
+// newEmailService creates the email service from the loaded configuration
+func newEmailService() (*email.EmailService, error) {
+	emailService := email.NewEmailService(cfg.SMTPSettings)
+	
+	transport, err := email.NewTransport(cfg.Mail, cfg.SMTPSettings)
+	if err != nil {
+		return nil, err
+	}
+	emailService.SetTransport(transport)
+	
+	emailService.DescriptionLength = cfg.DescriptionLength
+	emailService.Covers = api.NewCoverCache(coverCacheDir())
+	
+	signer, err := newUnsubscribeSigner()
+	if err != nil {
+		return nil, err
+	}
+	emailService.Unsubscribe = signer
+	
+	emailService.OnRefreshTokenRotated(func(refreshToken string) {
+		cfg.SMTPSettings.OAuth.RefreshToken = refreshToken
+		if err := cfg.Save(cfgFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to save rotated SMTP refresh token: %v\n", err)
+		}
+	})
+	
+	if cfg.TemplateDir != "" {
+		if err := emailService.LoadTemplates(cfg.TemplateDir); err != nil {
+			return nil, fmt.Errorf("failed to load email templates: %w", err)
+		}
+	}
+	
+	return emailService, nil
+}
+
+// coverCacheDir returns where cover thumbnails are cached, by default a
+// "covers" directory next to the database
+func coverCacheDir() string {
+	if cfg.CoverCacheDir != "" {
+		return cfg.CoverCacheDir
+	}
+	return filepath.Join(filepath.Dir(cfg.DatabasePath), "covers")
+}
+
+// newUnsubscribeSigner creates the unsubscribe link signer, generating and
+// saving a secret on first use. It returns nil if links are not configured.
+func newUnsubscribeSigner() (*unsubscribe.Signer, error) {
+	if cfg.Unsubscribe.BaseURL == "" {
+		return nil, nil
+	}
+	
+	if cfg.Unsubscribe.Secret == "" {
+		secret, err := unsubscribe.GenerateSecret()
+		if err != nil {
+			return nil, err
+		}
+		cfg.Unsubscribe.Secret = secret
+		if err := cfg.Save(cfgFile); err != nil {
+			return nil, fmt.Errorf("failed to save unsubscribe secret: %w", err)
+		}
+	}
+	
+	return unsubscribe.NewSigner(cfg.Unsubscribe.Secret, cfg.Unsubscribe.BaseURL), nil
+}
+
+// newNotifiers creates the notification channel registry from the loaded configuration
+func newNotifiers() (*notify.Registry, error) {
+	emailService, err := newEmailService()
+	if err != nil {
+		return nil, err
+	}
+	
+	notifiers, err := notify.NewRegistry(emailService, cfg.Notifiers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up notification channels: %w", err)
+	}
+	
+	return notifiers, nil
+}
+
+// newUpdateEngine creates the update engine from the loaded configuration
+func newUpdateEngine(client *api.MangaDexClient) (*updater.Engine, error) {
+	notifiers, err := newNotifiers()
+	if err != nil {
+		return nil, err
+	}
+	
+	engine := updater.NewEngine(database, client, notifiers)
+	engine.FeedMode = cfg.UseFollowFeed
+	if len(cfg.ContentRatings) > 0 {
+		engine.ContentRatings = cfg.ContentRatings
+	}
+	if cfg.OutboxMaxAttempts > 0 {
+		engine.MaxDeliveryAttempts = cfg.OutboxMaxAttempts
+	}
+	if cfg.CheckWorkers > 0 {
+		engine.Workers = cfg.CheckWorkers
+	}
+	return engine, nil
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() error {
 	return rootCmd.Execute()
@@ -102,4 +251,7 @@ func init() {
 	rootCmd.AddCommand(subscriptionCmd)
 	rootCmd.AddCommand(serviceCmd)
 	rootCmd.AddCommand(checkCmd)
+	rootCmd.AddCommand(userCmd)
+	rootCmd.AddCommand(outboxCmd)
+	rootCmd.AddCommand(cacheCmd)
 }
\ No newline at end of file
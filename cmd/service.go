@@ -1,21 +1,27 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	"mangadex-cli/internal/api"
-	"mangadex-cli/internal/email"
 	"mangadex-cli/internal/scheduler"
+	"mangadex-cli/internal/unsubscribe"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	daemonize  bool
-	foreground bool
+	daemonize       bool
+	foreground      bool
+	shutdownTimeout time.Duration
 )
 
 // serviceCmd represents the service command
@@ -30,28 +36,19 @@ var startCmd = &cobra.Command{
 	Use:   "start",
 	Short: "Start the notification service",
 	Long: `Start the notification service that checks for manga updates.
-The service can run in the foreground or as a background process.`,
+The service can run in the foreground or as a background process.
+On Ctrl+C, an update run in progress is given --shutdown-timeout to finish
+before it is cancelled.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Initialize API client
-		client := api.NewMangaDexClient(cfg.MangaDexAPIURL)
-		
-		// Set auth token if available
-		if cfg.AuthToken != "" {
-			client.SessionToken = cfg.AuthToken
-			client.RefreshToken = cfg.RefreshToken
-			client.TokenExpiry = cfg.TokenExpiry
-		}
-		
-		// Initialize email service
-		emailService := email.NewEmailService(cfg.SMTPSettings)
+		client := newAPIClient()
 		
 		// Initialize scheduler
-		sched := scheduler.NewCronScheduler(
-			database,
-			client,
-			emailService,
-			cfg.UpdateCheckInterval,
-		)
+		engine, err := newUpdateEngine(client)
+		if err != nil {
+			return err
+		}
+		sched := scheduler.NewCronScheduler(engine, cfg.UpdateCheckInterval)
 		
 		// Start the scheduler
 		if err := sched.Start(); err != nil {
@@ -60,6 +57,13 @@ The service can run in the foreground or as a background process.`,
 		
 		fmt.Printf("Notification service started. Checking for updates every %d seconds\n", cfg.UpdateCheckInterval)
 		
+		// Serve unsubscribe links
+		server, err := startUnsubscribeServer()
+		if err != nil {
+			sched.Stop()
+			return err
+		}
+		
 		// If running in the foreground, wait for interruption
 		if foreground {
 			fmt.Println("Press Ctrl+C to stop the service")
@@ -71,8 +75,15 @@ The service can run in the foreground or as a background process.`,
 			// Wait for signal
 			<-sigChan
 			
-			// Stop the scheduler
-			if err := sched.Stop(); err != nil {
+			// Stop the unsubscribe handler and the scheduler
+			if server != nil {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if err := server.Shutdown(ctx); err != nil {
+					return fmt.Errorf("failed to stop unsubscribe handler: %w", err)
+				}
+			}
+			if err := sched.Shutdown(shutdownTimeout); err != nil {
 				return fmt.Errorf("failed to stop scheduler: %w", err)
 			}
 			
@@ -97,6 +108,50 @@ var statusCmd = &cobra.Command{
 	},
 }
 
+// startUnsubscribeServer serves the unsubscribe handler on the configured
+// address, at the path of the configured link URL. It returns nil if no
+// address is configured.
+func startUnsubscribeServer() (*http.Server, error) {
+	if cfg.Unsubscribe.ListenAddr == "" {
+		return nil, nil
+	}
+	
+	signer, err := newUnsubscribeSigner()
+	if err != nil {
+		return nil, err
+	}
+	if signer == nil {
+		return nil, fmt.Errorf("unsubscribe base URL must be set to serve unsubscribe links")
+	}
+	
+	base, err := url.Parse(cfg.Unsubscribe.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid unsubscribe base URL: %w", err)
+	}
+	path := base.Path
+	if path == "" {
+		path = "/"
+	}
+	
+	mux := http.NewServeMux()
+	mux.Handle(path, unsubscribe.NewHandler(database, signer))
+	
+	listener, err := net.Listen("tcp", cfg.Unsubscribe.ListenAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serve unsubscribe handler: %w", err)
+	}
+	
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("Unsubscribe handler stopped: %v", err)
+		}
+	}()
+	
+	fmt.Printf("Serving unsubscribe links on %s%s\n", cfg.Unsubscribe.ListenAddr, path)
+	return server, nil
+}
+
 func init() {
 	serviceCmd.AddCommand(startCmd)
 	serviceCmd.AddCommand(statusCmd)
@@ -104,4 +159,5 @@ func init() {
 	// Add flags for start command
 	startCmd.Flags().BoolVarP(&daemonize, "daemon", "d", false, "Run as a daemon (background process)")
 	startCmd.Flags().BoolVarP(&foreground, "foreground", "f", false, "Run in the foreground")
+	startCmd.Flags().DurationVar(&shutdownTimeout, "shutdown-timeout", 30*time.Second, "How long to wait for a running update check before cancelling it")
 }
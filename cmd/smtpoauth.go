@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"mangadex-cli/internal/email"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	oauthProvider          string
+	oauthTenantID          string
+	oauthClientID          string
+	oauthClientSecret      string
+	oauthScope             string
+	oauthClientCredentials bool
+)
+
+// smtpOAuthCmd represents the config smtp-oauth command
+var smtpOAuthCmd = &cobra.Command{
+	Use:   "smtp-oauth",
+	Short: "Set up XOAUTH2 authentication for SMTP",
+	Long: `Set up OAuth2 (XOAUTH2) SMTP authentication for providers that have
+disabled password authentication, such as Gmail and Microsoft 365.
+
+By default you sign in as the SMTP username through a link shown here. For
+Google, open it in a browser on this machine: it redirects back to a temporary
+local server. For Microsoft, open it anywhere and enter the code shown (device
+authorization). The refresh token is saved in the configuration and access
+tokens are renewed automatically.
+
+With --client-credentials no sign-in is needed; access tokens are requested
+with the client secret instead (Microsoft 365 app-only access).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings := cfg.SMTPSettings.OAuth
+		if cmd.Flags().Changed("provider") {
+			settings.Provider = strings.ToLower(oauthProvider)
+		}
+		if cmd.Flags().Changed("tenant") {
+			settings.TenantID = oauthTenantID
+		}
+		if cmd.Flags().Changed("client-id") {
+			settings.ClientID = oauthClientID
+		}
+		if cmd.Flags().Changed("client-secret") {
+			settings.ClientSecret = oauthClientSecret
+		}
+		if cmd.Flags().Changed("scope") {
+			settings.Scope = oauthScope
+		}
+
+		if settings.ClientID == "" {
+			return fmt.Errorf("client ID must be provided")
+		}
+		if cfg.SMTPSettings.Username == "" {
+			return fmt.Errorf("SMTP username must be set to the mailbox address first")
+		}
+		endpoints, err := email.ResolveOAuthEndpoints(settings)
+		if err != nil {
+			return err
+		}
+
+		// Ctrl+C abandons the sign-in
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		if oauthClientCredentials {
+			if settings.ClientSecret == "" {
+				return fmt.Errorf("client secret must be provided for client credentials")
+			}
+
+			// Check the credentials work before saving them
+			settings.RefreshToken = ""
+			if _, err := email.NewTokenSource(settings).TokenContext(ctx); err != nil {
+				return err
+			}
+			fmt.Println("Access token received with client credentials")
+		} else if endpoints.DeviceAuthURL == "" {
+			refreshToken, err := email.AuthorizeLoopback(ctx, settings, func(authURL string) {
+				fmt.Printf("To authorize SMTP access, open this link in a browser on this machine:\n%s\n", authURL)
+				fmt.Println("Waiting for authorization...")
+			})
+			if err != nil {
+				return err
+			}
+			settings.RefreshToken = refreshToken
+			fmt.Println("Authorization complete")
+		} else {
+			code, err := email.StartDeviceFlow(ctx, settings)
+			if err != nil {
+				return err
+			}
+
+			if code.Message != "" {
+				fmt.Println(code.Message)
+			} else {
+				fmt.Printf("To authorize SMTP access, open %s and enter the code %s\n", code.URL(), code.UserCode)
+			}
+			fmt.Println("Waiting for authorization...")
+
+			refreshToken, err := email.PollDeviceFlow(ctx, settings, code)
+			if err != nil {
+				return err
+			}
+			settings.RefreshToken = refreshToken
+			fmt.Println("Authorization complete")
+		}
+
+		cfg.SMTPSettings.OAuth = settings
+		cfg.SMTPSettings.AuthMechanism = email.AuthXOAUTH2
+
+		// Save config
+		if err := cfg.Save(cfgFile); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+
+		fmt.Printf("XOAUTH2 authentication saved for %s; check it with 'config test email'\n", cfg.SMTPSettings.Username)
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(smtpOAuthCmd)
+
+	smtpOAuthCmd.Flags().StringVar(&oauthProvider, "provider", "", "OAuth provider (google, microsoft)")
+	smtpOAuthCmd.Flags().StringVar(&oauthTenantID, "tenant", "", "Microsoft tenant ID (default organizations)")
+	smtpOAuthCmd.Flags().StringVar(&oauthClientID, "client-id", "", "OAuth client ID")
+	smtpOAuthCmd.Flags().StringVar(&oauthClientSecret, "client-secret", "", "OAuth client secret, if the client has one")
+	smtpOAuthCmd.Flags().StringVar(&oauthScope, "scope", "", "Scope to request instead of the provider default")
+	smtpOAuthCmd.Flags().BoolVar(&oauthClientCredentials, "client-credentials", false, "Use the client credentials grant instead of signing in")
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strconv"
@@ -20,6 +21,12 @@ var (
 	userEmail     string
 	subscriptionID int
 	languages     string
+	syncFollows   bool
+	preferGroups  string
+	blockGroups   string
+	dedupPolicy   string
+	dedupWindow   int
+	contentRatings string
 )
 
 // subscriptionCmd represents the subscription command
@@ -37,14 +44,7 @@ var addCmd = &cobra.Command{
 You can specify a manga by title (search) or by its MangaDex ID.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Initialize API client
-		client := api.NewMangaDexClient(cfg.MangaDexAPIURL)
-		
-		// Set auth token if available
-		if cfg.AuthToken != "" {
-			client.SessionToken = cfg.AuthToken
-			client.RefreshToken = cfg.RefreshToken
-			client.TokenExpiry = cfg.TokenExpiry
-		}
+		client := newAPIClient()
 		
 		// Validate required parameters
 		if mangaTitle == "" && mangaID == "" {
@@ -56,30 +56,25 @@ You can specify a manga by title (search) or by its MangaDex ID.`,
 		}
 		
 		// Get user or create if doesn't exist
-		user, err := database.GetUserByEmail(userEmail)
+		user, err := getOrCreateUser(userEmail)
 		if err != nil {
-			// Create new user if not found
-			user = &db.User{
-				Email:     userEmail,
-				Active:    true,
-				CreatedAt: time.Now(),
-				UpdatedAt: time.Now(),
-			}
-			if err := database.AddUser(user); err != nil {
-				return fmt.Errorf("failed to create user: %w", err)
-			}
+			return err
 		}
 		
 		// Parse language preferences
-		var languageList []string
-		if languages != "" {
-			languageList = strings.Split(languages, ",")
-			for i, lang := range languageList {
-				languageList[i] = strings.TrimSpace(lang)
-			}
-		} else {
-			// Default to English
-			languageList = []string{"en"}
+		languageList := parseLanguages(languages)
+		
+		// Parse content ratings; empty uses the configured default
+		ratingList, err := parseContentRatings(contentRatings)
+		if err != nil {
+			return err
+		}
+		
+		// Validate duplicate release policy
+		switch dedupPolicy {
+		case db.DedupAll, db.DedupFirst, db.DedupGroup, db.DedupWait:
+		default:
+			return fmt.Errorf("invalid dedup policy %q, must be all, first, group or wait", dedupPolicy)
 		}
 		
 		var manga *api.Manga
@@ -134,6 +129,11 @@ You can specify a manga by title (search) or by its MangaDex ID.`,
 			MangaID:        manga.ID,
 			MangaTitle:     manga.GetTitle(),
 			Languages:      strings.Join(languageList, ","),
+			PreferredGroups: strings.Join(parseList(preferGroups), ","),
+			BlockedGroups:  strings.Join(parseList(blockGroups), ","),
+			DedupPolicy:    dedupPolicy,
+			DedupWindow:    dedupWindow,
+			ContentRatings: strings.Join(ratingList, ","),
 			LastCheckTime:  time.Now(),
 			LastChapterTime: time.Now(),
 			Active:         true,
@@ -147,6 +147,13 @@ You can specify a manga by title (search) or by its MangaDex ID.`,
 		}
 		
 		fmt.Printf("Successfully subscribed to \"%s\" for %s\n", manga.GetTitle(), userEmail)
+		
+		// Chapters of a manga with an excluded rating are never returned
+		effective := subscription.GetContentRatings(defaultContentRatings())
+		if manga.ContentRating != "" && !containsString(effective, manga.ContentRating) {
+			fmt.Printf("Warning: \"%s\" is rated %s, which is excluded by the content ratings %s, so no chapters will be notified. Use --content-ratings to include it.\n",
+				manga.GetTitle(), manga.ContentRating, strings.Join(effective, ","))
+		}
 		return nil
 	},
 }
@@ -229,7 +236,7 @@ var listCmd = &cobra.Command{
 		
 		// Display subscriptions
 		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"ID", "Manga Title", "User Email", "Languages", "Last Check", "Status"})
+		table.SetHeader([]string{"ID", "Manga Title", "User Email", "Languages", "Groups", "Last Check", "Status"})
 		
 		for _, sub := range subscriptions {
 			// Get user email
@@ -249,6 +256,7 @@ var listCmd = &cobra.Command{
 				sub.MangaTitle,
 				user.Email,
 				sub.Languages,
+				describeGroups(sub),
 				sub.LastCheckTime.Format("2006-01-02 15:04"),
 				status,
 			}
@@ -260,16 +268,236 @@ var listCmd = &cobra.Command{
 	},
 }
 
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import mangadex",
+	Short: "Import subscriptions from followed manga",
+	Long: `Create a subscription for every manga followed by the logged-in MangaDex account.
+Series that are already subscribed are skipped. With --sync, subscriptions
+created by an import for series that are no longer followed are deactivated,
+and inactive subscriptions for followed series are reactivated. Subscriptions
+added with 'subscription add' are never deactivated by --sync.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		source := strings.ToLower(args[0])
+		if source != "mangadex" {
+			return fmt.Errorf("unknown import source: %s", source)
+		}
+		
+		if userEmail == "" {
+			return fmt.Errorf("user email must be provided")
+		}
+		
+		if cfg.AuthToken == "" {
+			return fmt.Errorf("not logged in, run 'mangadex-cli auth login' first")
+		}
+		
+		// Initialize API client
+		client := newAPIClient()
+		
+		// Get followed manga, bypassing the cache so recent follows and
+		// unfollows are seen
+		followed, err := client.RefreshFollowedManga(context.Background())
+		if err != nil {
+			return fmt.Errorf("failed to get followed manga: %w", err)
+		}
+		
+		// Get user or create if doesn't exist
+		user, err := getOrCreateUser(userEmail)
+		if err != nil {
+			return err
+		}
+		
+		// Index existing subscriptions by manga
+		existing, err := database.GetSubscriptionsByUserID(user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get subscriptions: %w", err)
+		}
+		
+		subscribed := make(map[string][]*db.Subscription, len(existing))
+		for i := range existing {
+			subscribed[existing[i].MangaID] = append(subscribed[existing[i].MangaID], &existing[i])
+		}
+		
+		languageList := parseLanguages(languages)
+		imported, reactivated, deactivated := 0, 0, 0
+		
+		// Subscribe to every followed manga
+		follows := make(map[string]bool, len(followed))
+		for _, manga := range followed {
+			follows[manga.ID] = true
+			
+			if subs, ok := subscribed[manga.ID]; ok {
+				for _, sub := range subs {
+					if !syncFollows || sub.Active {
+						continue
+					}
+					sub.Active = true
+					sub.LastCheckTime = time.Now()
+					sub.CheckTruncated = false
+					if err := database.UpdateSubscription(sub); err != nil {
+						return fmt.Errorf("failed to reactivate subscription for \"%s\": %w", sub.MangaTitle, err)
+					}
+					fmt.Printf("Reactivated \"%s\"\n", sub.MangaTitle)
+					reactivated++
+				}
+				continue
+			}
+			
+			subscription := &db.Subscription{
+				UserID:         user.ID,
+				MangaID:        manga.ID,
+				MangaTitle:     manga.GetTitle(),
+				Languages:      strings.Join(languageList, ","),
+				LastCheckTime:  time.Now(),
+				LastChapterTime: time.Now(),
+				Active:         true,
+				Imported:       true,
+				CreatedAt:      time.Now(),
+				UpdatedAt:      time.Now(),
+			}
+			
+			if err := database.AddSubscription(subscription); err != nil {
+				return fmt.Errorf("failed to add subscription for \"%s\": %w", manga.GetTitle(), err)
+			}
+			fmt.Printf("Subscribed to \"%s\"\n", manga.GetTitle())
+			imported++
+		}
+		
+		// Deactivate imported subscriptions for unfollowed manga, leaving
+		// subscriptions added by hand alone
+		if syncFollows {
+			for i := range existing {
+				sub := &existing[i]
+				if follows[sub.MangaID] || !sub.Active || !sub.Imported {
+					continue
+				}
+				
+				sub.Active = false
+				if err := database.UpdateSubscription(sub); err != nil {
+					return fmt.Errorf("failed to deactivate subscription for \"%s\": %w", sub.MangaTitle, err)
+				}
+				fmt.Printf("Deactivated \"%s\"\n", sub.MangaTitle)
+				deactivated++
+			}
+		}
+		
+		fmt.Printf("Imported %d of %d followed manga for %s", imported, len(followed), userEmail)
+		if syncFollows {
+			fmt.Printf(" (%d reactivated, %d deactivated)", reactivated, deactivated)
+		}
+		fmt.Println()
+		
+		return nil
+	},
+}
+
+// getOrCreateUser gets a user by email, creating it if it doesn't exist
+func getOrCreateUser(email string) (*db.User, error) {
+	user, err := database.GetUserByEmail(email)
+	if err == nil {
+		return user, nil
+	}
+	
+	// Create new user if not found
+	user = &db.User{
+		Email:     email,
+		Active:    true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := database.AddUser(user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	
+	return user, nil
+}
+
+// parseLanguages splits a comma-separated list of language codes, defaulting to English
+func parseLanguages(value string) []string {
+	if value == "" {
+		return []string{"en"}
+	}
+	
+	languageList := strings.Split(value, ",")
+	for i, lang := range languageList {
+		languageList[i] = strings.TrimSpace(lang)
+	}
+	
+	return languageList
+}
+
+// describeGroups summarizes a subscription's scanlation group preferences
+func describeGroups(sub db.Subscription) string {
+	parts := make([]string, 0, 2)
+	if sub.PreferredGroups != "" {
+		parts = append(parts, "prefer: "+sub.PreferredGroups)
+	}
+	if sub.BlockedGroups != "" {
+		parts = append(parts, "block: "+sub.BlockedGroups)
+	}
+	if len(parts) == 0 {
+		return "any"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// parseContentRatings splits and validates a comma-separated list of content ratings
+func parseContentRatings(value string) ([]string, error) {
+	ratings := parseList(strings.ToLower(value))
+	for _, rating := range ratings {
+		if !containsString(api.ContentRatings, rating) {
+			return nil, fmt.Errorf("invalid content rating %q, must be one of %s", rating, strings.Join(api.ContentRatings, ", "))
+		}
+	}
+	return ratings, nil
+}
+
+// defaultContentRatings returns the content ratings used by subscriptions that set none
+func defaultContentRatings() []string {
+	if len(cfg.ContentRatings) > 0 {
+		return cfg.ContentRatings
+	}
+	return api.DefaultContentRatings
+}
+
+// containsString reports whether a list contains a value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// parseList splits a comma-separated list, dropping empty items
+func parseList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func init() {
 	subscriptionCmd.AddCommand(addCmd)
 	subscriptionCmd.AddCommand(removeCmd)
 	subscriptionCmd.AddCommand(listCmd)
+	subscriptionCmd.AddCommand(importCmd)
 	
 	// Add flags for add command
 	addCmd.Flags().StringVarP(&mangaTitle, "title", "t", "", "Manga title to search for")
 	addCmd.Flags().StringVarP(&mangaID, "id", "i", "", "MangaDex manga ID")
 	addCmd.Flags().StringVarP(&userEmail, "email", "e", "", "User email address")
 	addCmd.Flags().StringVarP(&languages, "languages", "l", "en", "Comma-separated language codes (e.g., 'en,es,fr')")
+	addCmd.Flags().StringVar(&preferGroups, "prefer-groups", "", "Comma-separated scanlation group names or IDs to prefer when a chapter has several releases")
+	addCmd.Flags().StringVar(&blockGroups, "block-groups", "", "Comma-separated scanlation group names or IDs to ignore")
+	addCmd.Flags().StringVar(&contentRatings, "content-ratings", "", "Comma-separated content ratings to notify (safe, suggestive, erotica, pornographic); defaults to the configured content ratings")
+	addCmd.Flags().StringVar(&dedupPolicy, "dedup", db.DedupAll, "Duplicate release policy: all, first (first release only), group (once per group) or wait")
+	addCmd.Flags().IntVar(&dedupWindow, "dedup-window", 60, "Minutes to wait for other releases of a chapter with --dedup wait")
 	
 	// Add flags for remove command
 	removeCmd.Flags().IntVarP(&subscriptionID, "id", "i", 0, "Subscription ID to remove")
@@ -277,4 +505,10 @@ func init() {
 	
 	// Add flags for list command
 	listCmd.Flags().StringVarP(&userEmail, "email", "e", "", "Filter subscriptions by user email")
+	
+	// Add flags for import command
+	importCmd.Flags().StringVarP(&userEmail, "email", "e", "", "User email address")
+	importCmd.Flags().StringVarP(&languages, "languages", "l", "en", "Comma-separated language codes for new subscriptions (e.g., 'en,es,fr')")
+	importCmd.Flags().BoolVar(&syncFollows, "sync", false, "Deactivate imported subscriptions for manga that are no longer followed and reactivate followed ones")
+	importCmd.MarkFlagRequired("email")
 }
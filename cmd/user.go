@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"mangadex-cli/internal/db"
+	"mangadex-cli/internal/updater"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	channels        string
+	deliveryMode    string
+	deliveryTime    string
+	deliveryWeekday string
+)
+
+// userCmd represents the user command
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage notification recipients",
+	Long:  `Commands for managing how users receive notifications.`,
+}
+
+// userChannelsCmd represents the user channels command
+var userChannelsCmd = &cobra.Command{
+	Use:   "channels",
+	Short: "Show or set a user's notification channels",
+	Long: `Show the notification channels a user receives updates on, or replace them
+with --set. Channels are "email" or names added with 'config notifier add'.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		user, err := database.GetUserByEmail(userEmail)
+		if err != nil {
+			return fmt.Errorf("failed to find user with email %s: %w", userEmail, err)
+		}
+		
+		if !cmd.Flags().Changed("set") {
+			fmt.Printf("Channels for %s: %s\n", user.Email, strings.Join(user.GetChannels(), ", "))
+			return nil
+		}
+		
+		// Validate channel names
+		channelList := make([]string, 0)
+		for _, channel := range strings.Split(channels, ",") {
+			channel = strings.TrimSpace(channel)
+			if channel == "" {
+				continue
+			}
+			if _, ok := cfg.Notifiers[channel]; !ok && channel != db.DefaultChannel {
+				return fmt.Errorf("unknown notification channel: %s", channel)
+			}
+			channelList = append(channelList, channel)
+		}
+		
+		if len(channelList) == 0 {
+			return fmt.Errorf("at least one channel must be provided")
+		}
+		
+		user.Channels = strings.Join(channelList, ",")
+		if err := database.UpdateUser(user); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		
+		fmt.Printf("Channels for %s set to: %s\n", user.Email, strings.Join(channelList, ", "))
+		return nil
+	},
+}
+
+// userDeliveryCmd represents the user delivery command
+var userDeliveryCmd = &cobra.Command{
+	Use:   "delivery",
+	Short: "Show or set a user's delivery schedule",
+	Long: `Show how often a user receives notifications, or change it with --mode.
+Modes are immediate (one message per series on every check), hourly, daily
+(at --at, default 08:00) and weekly (on --weekday at --at). Hourly, daily and
+weekly users receive one digest grouped by series; new chapters are held in
+the database until it is due. Times use the host's local time zone.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		user, err := database.GetUserByEmail(userEmail)
+		if err != nil {
+			return fmt.Errorf("failed to find user with email %s: %w", userEmail, err)
+		}
+		
+		if !cmd.Flags().Changed("mode") {
+			fmt.Printf("Delivery for %s: %s\n", user.Email, describeDelivery(user))
+			return nil
+		}
+		
+		mode := strings.ToLower(deliveryMode)
+		switch mode {
+		case db.DeliveryImmediate, db.DeliveryHourly:
+			deliveryTime, deliveryWeekday = "", ""
+		case db.DeliveryDaily:
+			deliveryWeekday = ""
+		case db.DeliveryWeekly:
+			if _, ok := updater.ParseWeekday(deliveryWeekday); !ok {
+				return fmt.Errorf("invalid weekday: %s", deliveryWeekday)
+			}
+		default:
+			return fmt.Errorf("unknown delivery mode: %s", deliveryMode)
+		}
+		
+		if deliveryTime != "" {
+			if _, _, ok := updater.ParseClock(deliveryTime); !ok {
+				return fmt.Errorf("invalid time, must be HH:MM: %s", deliveryTime)
+			}
+		}
+		
+		user.DeliveryMode = mode
+		user.DeliveryTime = deliveryTime
+		user.DeliveryWeekday = deliveryWeekday
+		user.LastDigestAt = time.Now() // First digest at the next slot
+		if err := database.UpdateUser(user); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		
+		fmt.Printf("Delivery for %s set to: %s\n", user.Email, describeDelivery(user))
+		return nil
+	},
+}
+
+// describeDelivery returns a human readable delivery schedule
+func describeDelivery(user *db.User) string {
+	at := user.DeliveryTime
+	if at == "" {
+		at = "08:00"
+	}
+	
+	switch user.GetDeliveryMode() {
+	case db.DeliveryDaily:
+		return fmt.Sprintf("daily digest at %s", at)
+	case db.DeliveryWeekly:
+		weekday, _ := updater.ParseWeekday(user.DeliveryWeekday)
+		return fmt.Sprintf("weekly digest on %s at %s", weekday, at)
+	default:
+		return user.GetDeliveryMode()
+	}
+}
+
+func init() {
+	userCmd.AddCommand(userChannelsCmd)
+	userCmd.AddCommand(userDeliveryCmd)
+	
+	// Add flags for channels command
+	userChannelsCmd.Flags().StringVarP(&userEmail, "email", "e", "", "User email address")
+	userChannelsCmd.Flags().StringVarP(&channels, "set", "s", "", "Comma-separated channel names (e.g., 'email,team-discord')")
+	userChannelsCmd.MarkFlagRequired("email")
+	
+	// Add flags for delivery command
+	userDeliveryCmd.Flags().StringVarP(&userEmail, "email", "e", "", "User email address")
+	userDeliveryCmd.Flags().StringVarP(&deliveryMode, "mode", "m", "", "Delivery mode (immediate, hourly, daily, weekly)")
+	userDeliveryCmd.Flags().StringVar(&deliveryTime, "at", "", "Digest time of day as HH:MM (daily and weekly)")
+	userDeliveryCmd.Flags().StringVar(&deliveryWeekday, "weekday", "monday", "Digest weekday (weekly)")
+	userDeliveryCmd.MarkFlagRequired("email")
+}
@@ -0,0 +1,264 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoint types, for cache TTLs
+const (
+	EndpointManga    = "manga"    // A manga's details: /manga/{id}
+	EndpointSearch   = "search"   // Manga searches: /manga
+	EndpointChapter  = "chapter"  // A chapter's details: /chapter/{id}
+	EndpointChapters = "chapters" // Chapter lists and feeds
+	EndpointFollows  = "follows"  // The followed manga list
+	EndpointOther    = "other"
+)
+
+// EndpointTypes lists every endpoint type
+var EndpointTypes = []string{EndpointManga, EndpointSearch, EndpointChapter, EndpointChapters, EndpointFollows, EndpointOther}
+
+// DefaultCacheTTLs is how long responses are reused without asking the
+// server again. Chapter lists are what update checks look for, so they are
+// never cached by default. The follow list rarely changes but is read by
+// every feed mode check, so it is kept for a while; imports revalidate it
+// so they never act on an outdated list.
+var DefaultCacheTTLs = map[string]time.Duration{
+	EndpointManga:   24 * time.Hour,
+	EndpointSearch:  time.Hour,
+	EndpointChapter: 24 * time.Hour,
+	EndpointFollows: time.Hour,
+}
+
+// EndpointType classifies an API path
+func EndpointType(endpoint string) string {
+	parts := strings.Split(strings.Trim(endpoint, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "manga":
+		return EndpointSearch
+	case len(parts) == 2 && parts[0] == "manga":
+		return EndpointManga
+	case len(parts) == 1 && parts[0] == "chapter":
+		return EndpointChapters
+	case len(parts) == 2 && parts[0] == "chapter":
+		return EndpointChapter
+	case len(parts) >= 3 && parts[len(parts)-1] == "feed":
+		return EndpointChapters
+	case endpoint == "/user/follows/manga":
+		return EndpointFollows
+	default:
+		return EndpointOther
+	}
+}
+
+// CacheEntry is a cached response body and its validators
+type CacheEntry struct {
+	URL          string    `json:"url"`
+	Type         string    `json:"type"` // Endpoint type
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	StoredAt     time.Time `json:"stored_at"` // When the response was last fetched or revalidated
+	Body         []byte    `json:"body"`
+}
+
+// ResponseCache stores GET responses on disk, one file per URL. Fresh
+// entries are returned without a request; stale ones are revalidated with
+// If-None-Match or If-Modified-Since when the server sent validators.
+type ResponseCache struct {
+	Dir  string
+	TTLs map[string]time.Duration // Endpoint type -> TTL; zero or missing disables caching
+
+	mu sync.Mutex
+}
+
+// CacheStats summarizes the entries of one endpoint type
+type CacheStats struct {
+	Type    string
+	Entries int
+	Expired int
+	Bytes   int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// NewResponseCache creates a response cache in dir with the default TTLs
+func NewResponseCache(dir string) *ResponseCache {
+	ttls := make(map[string]time.Duration, len(DefaultCacheTTLs))
+	for endpointType, ttl := range DefaultCacheTTLs {
+		ttls[endpointType] = ttl
+	}
+	return &ResponseCache{Dir: dir, TTLs: ttls}
+}
+
+// TTL returns how long responses of an endpoint type are cached
+func (c *ResponseCache) TTL(endpointType string) time.Duration {
+	return c.TTLs[endpointType]
+}
+
+// Get returns the entry for a URL, or nil if there is none
+func (c *ResponseCache) Get(url string) *CacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, err := readCacheEntry(c.path(url))
+	if err != nil || entry.URL != url {
+		return nil
+	}
+	return entry
+}
+
+// Fresh reports whether an entry can be used without revalidating it
+func (c *ResponseCache) Fresh(entry *CacheEntry) bool {
+	return time.Since(entry.StoredAt) < c.TTL(entry.Type)
+}
+
+// Put stores a response, replacing any earlier entry for its URL
+func (c *ResponseCache) Put(entry *CacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Write a temporary file first so readers never see a partial entry
+	path := c.path(entry.URL)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Stats summarizes the cache by endpoint type
+func (c *ResponseCache) Stats() ([]CacheStats, error) {
+	stats := make(map[string]*CacheStats)
+	err := c.walk(func(path string, entry *CacheEntry, size int64) error {
+		s, ok := stats[entry.Type]
+		if !ok {
+			s = &CacheStats{Type: entry.Type}
+			stats[entry.Type] = s
+		}
+		s.Entries++
+		s.Bytes += size
+		if !c.Fresh(entry) {
+			s.Expired++
+		}
+		if s.Oldest.IsZero() || entry.StoredAt.Before(s.Oldest) {
+			s.Oldest = entry.StoredAt
+		}
+		if entry.StoredAt.After(s.Newest) {
+			s.Newest = entry.StoredAt
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]CacheStats, 0, len(stats))
+	for _, endpointType := range EndpointTypes {
+		if s, ok := stats[endpointType]; ok {
+			result = append(result, *s)
+		}
+	}
+	return result, nil
+}
+
+// Clear removes cached entries, only expired ones if expiredOnly is set,
+// and returns how many were removed
+func (c *ResponseCache) Clear(expiredOnly bool) (int, error) {
+	removed := 0
+	err := c.walk(func(path string, entry *CacheEntry, size int64) error {
+		if expiredOnly && c.Fresh(entry) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+// walk calls fn for every cache file. Unreadable files are passed with a
+// placeholder entry so Clear removes them.
+func (c *ResponseCache) walk(fn func(path string, entry *CacheEntry, size int64) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	files, err := os.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(c.Dir, file.Name())
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		entry, err := readCacheEntry(path)
+		if err != nil {
+			entry = &CacheEntry{Type: EndpointOther}
+		}
+		if err := fn(path, entry, info.Size()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// path returns the file an entry for a URL is stored in
+func (c *ResponseCache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// readCacheEntry reads a cache file
+func readCacheEntry(path string) (*CacheEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// setConditionalHeaders asks the server to answer 304 Not Modified if a
+// stale entry is still current
+func setConditionalHeaders(req *http.Request, entry *CacheEntry) {
+	if entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.LastModified)
+	}
+}
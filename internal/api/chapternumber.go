@@ -0,0 +1,270 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// chapterRangePattern matches chapter numbers like "10", "10.5" and ranges
+// like "21-24", optionally followed by text such as "Extra"
+var chapterRangePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)(?:\s*[-–~]\s*(\d+(?:\.\d+)?))?`)
+
+// ChapterNumber is the parsed volume and chapter number of a chapter.
+// MangaDex stores both as free text: chapters may be decimals ("10.5"),
+// ranges ("21-24"), text ("Extra"), or missing for oneshots and
+// volume-only releases.
+type ChapterNumber struct {
+	Volume       string  // Volume as published, empty if none
+	VolumeNumber float64 // Numeric volume, valid if HasVolume
+	HasVolume    bool
+	Chapter      string  // Chapter as published, empty if none
+	Start        float64 // First chapter number, valid if Numeric
+	End          float64 // Last chapter number of a range, equal to Start otherwise
+	Numeric      bool    // Chapter starts with a number
+}
+
+// ParseChapterNumber parses MangaDex volume and chapter attributes
+func ParseChapterNumber(volume, chapter string) ChapterNumber {
+	n := ChapterNumber{
+		Volume:  strings.TrimSpace(volume),
+		Chapter: strings.TrimSpace(chapter),
+	}
+
+	if v, err := strconv.ParseFloat(n.Volume, 64); err == nil {
+		n.VolumeNumber = v
+		n.HasVolume = true
+	}
+
+	if m := chapterRangePattern.FindStringSubmatch(n.Chapter); m != nil {
+		n.Start, _ = strconv.ParseFloat(m[1], 64)
+		n.End = n.Start
+		if m[2] != "" {
+			if end, err := strconv.ParseFloat(m[2], 64); err == nil && end >= n.Start {
+				n.End = end
+			}
+		}
+		n.Numeric = true
+	}
+
+	return n
+}
+
+// IsOneshot reports whether the release has neither a chapter nor a volume
+func (n ChapterNumber) IsOneshot() bool {
+	return n.Chapter == "" && n.Volume == ""
+}
+
+// IsVolumeOnly reports whether the release is a whole volume without a chapter number
+func (n ChapterNumber) IsVolumeOnly() bool {
+	return n.Chapter == "" && n.Volume != ""
+}
+
+// Compare orders chapter numbers: numbered volumes first in volume order,
+// then chapters without a volume. Within a volume, numbered chapters come in
+// numeric order, followed by text chapters, volume-only releases and oneshots.
+// It returns -1, 0 or 1.
+func (n ChapterNumber) Compare(other ChapterNumber) int {
+	if c := compareVolume(n, other); c != 0 {
+		return c
+	}
+
+	if n.rank() != other.rank() {
+		return compareFloat(float64(n.rank()), float64(other.rank()))
+	}
+	if n.Numeric {
+		if c := compareFloat(n.Start, other.Start); c != 0 {
+			return c
+		}
+		if c := compareFloat(n.End, other.End); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(n.Chapter, other.Chapter)
+}
+
+// rank orders kinds of release within a volume
+func (n ChapterNumber) rank() int {
+	switch {
+	case n.Numeric:
+		return 0
+	case n.Chapter != "":
+		return 1
+	case n.Volume != "":
+		return 2
+	default:
+		return 3
+	}
+}
+
+// compareVolume orders numbered volumes before text volumes before no volume
+func compareVolume(a, b ChapterNumber) int {
+	switch {
+	case a.HasVolume && b.HasVolume:
+		return compareFloat(a.VolumeNumber, b.VolumeNumber)
+	case a.HasVolume != b.HasVolume:
+		if a.HasVolume {
+			return -1
+		}
+		return 1
+	case (a.Volume == "") != (b.Volume == ""):
+		if a.Volume != "" {
+			return -1
+		}
+		return 1
+	default:
+		return strings.Compare(a.Volume, b.Volume)
+	}
+}
+
+// compareFloat returns -1, 0 or 1
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Label returns a short name for the release, e.g. "Ch. 10.5", "Ch. 21–24",
+// "Vol. 3" or "Oneshot"
+func (n ChapterNumber) Label() string {
+	switch {
+	case n.Numeric && n.End > n.Start:
+		return fmt.Sprintf("Ch. %s–%s", formatNumber(n.Start), formatNumber(n.End))
+	case n.Chapter != "":
+		return "Ch. " + n.Chapter
+	case n.Volume != "":
+		return "Vol. " + n.Volume
+	default:
+		return "Oneshot"
+	}
+}
+
+// formatRange formats a range of chapter numbers, or a single number if the
+// range has one chapter
+func formatRange(start, end float64) string {
+	if end > start {
+		return formatNumber(start) + "–" + formatNumber(end)
+	}
+	return formatNumber(start)
+}
+
+// isWhole reports whether a chapter number has no fractional part
+func isWhole(f float64) bool {
+	return f == math.Trunc(f)
+}
+
+// formatNumber formats a chapter number without trailing zeros
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// Number returns the parsed volume and chapter number of a chapter
+func (c *Chapter) Number() ChapterNumber {
+	return ParseChapterNumber(c.Volume, c.Chapter)
+}
+
+// SortChapters sorts chapters by volume and chapter number, keeping releases
+// of the same number in upload order
+func SortChapters(chapters []Chapter) {
+	sort.SliceStable(chapters, func(i, j int) bool {
+		if c := chapters[i].Number().Compare(chapters[j].Number()); c != 0 {
+			return c < 0
+		}
+		return chapters[i].CreatedAt.Before(chapters[j].CreatedAt)
+	})
+}
+
+// VolumeGroup is a run of sorted chapters from the same volume
+type VolumeGroup struct {
+	Volume   string // Empty for chapters without a volume
+	Chapters []Chapter
+}
+
+// GroupByVolume sorts chapters and splits them by volume
+func GroupByVolume(chapters []Chapter) []VolumeGroup {
+	sorted := make([]Chapter, len(chapters))
+	copy(sorted, chapters)
+	SortChapters(sorted)
+
+	groups := make([]VolumeGroup, 0)
+	for _, chapter := range sorted {
+		volume := strings.TrimSpace(chapter.Volume)
+		if len(groups) == 0 || groups[len(groups)-1].Volume != volume {
+			groups = append(groups, VolumeGroup{Volume: volume})
+		}
+		last := &groups[len(groups)-1]
+		last.Chapters = append(last.Chapters, chapter)
+	}
+
+	return groups
+}
+
+// Label summarizes the group, e.g. "Vol. 3: Ch. 21–24, 26" or "Ch. 101–102".
+// Consecutive whole chapter numbers are collapsed into ranges; fractional
+// chapters such as 10.5 are listed on their own, so gaps are not hidden.
+func (g VolumeGroup) Label() string {
+	parts := make([]string, 0)
+	var runStart, runEnd float64
+	inRun := false
+
+	flush := func() {
+		if !inRun {
+			return
+		}
+		parts = append(parts, formatRange(runStart, runEnd))
+		inRun = false
+	}
+
+	for _, chapter := range g.Chapters {
+		n := chapter.Number()
+		switch {
+		case !n.Numeric:
+			flush()
+			if n.Chapter != "" {
+				parts = append(parts, n.Chapter)
+			}
+		case !isWhole(n.Start) || !isWhole(n.End):
+			flush()
+			parts = append(parts, formatRange(n.Start, n.End))
+		case inRun && n.Start <= runEnd+1:
+			if n.End > runEnd {
+				runEnd = n.End
+			}
+		default:
+			flush()
+			runStart, runEnd, inRun = n.Start, n.End, true
+		}
+	}
+	flush()
+
+	// Drop repeats from releases of the same chapter by several groups
+	unique := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if len(unique) == 0 || unique[len(unique)-1] != part {
+			unique = append(unique, part)
+		}
+	}
+
+	label := ""
+	if len(unique) > 0 {
+		label = "Ch. " + strings.Join(unique, ", ")
+	}
+	switch {
+	case g.Volume != "" && label != "":
+		return "Vol. " + g.Volume + ": " + label
+	case g.Volume != "":
+		return "Vol. " + g.Volume
+	case label != "":
+		return label
+	default:
+		return "Oneshot"
+	}
+}
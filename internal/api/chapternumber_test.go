@@ -0,0 +1,145 @@
+package api
+
+import "testing"
+
+func TestParseChapterNumber(t *testing.T) {
+	tests := []struct {
+		volume, chapter string
+		want            ChapterNumber
+	}{
+		{"", "10", ChapterNumber{Chapter: "10", Start: 10, End: 10, Numeric: true}},
+		{"", "10.5", ChapterNumber{Chapter: "10.5", Start: 10.5, End: 10.5, Numeric: true}},
+		{"3", "21-24", ChapterNumber{Volume: "3", VolumeNumber: 3, HasVolume: true, Chapter: "21-24", Start: 21, End: 24, Numeric: true}},
+		{"", "24-21", ChapterNumber{Chapter: "24-21", Start: 24, End: 24, Numeric: true}},
+		{"", "5 Extra", ChapterNumber{Chapter: "5 Extra", Start: 5, End: 5, Numeric: true}},
+		{"", "Extra", ChapterNumber{Chapter: "Extra"}},
+		{"2", "", ChapterNumber{Volume: "2", VolumeNumber: 2, HasVolume: true}},
+		{"", "", ChapterNumber{}},
+		{" 1 ", " 7 ", ChapterNumber{Volume: "1", VolumeNumber: 1, HasVolume: true, Chapter: "7", Start: 7, End: 7, Numeric: true}},
+		{"Special", "1", ChapterNumber{Volume: "Special", Chapter: "1", Start: 1, End: 1, Numeric: true}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseChapterNumber(tt.volume, tt.chapter); got != tt.want {
+			t.Errorf("ParseChapterNumber(%q, %q) = %+v, want %+v", tt.volume, tt.chapter, got, tt.want)
+		}
+	}
+}
+
+func TestChapterNumberKinds(t *testing.T) {
+	tests := []struct {
+		volume, chapter     string
+		oneshot, volumeOnly bool
+		label               string
+	}{
+		{"", "10.5", false, false, "Ch. 10.5"},
+		{"", "21-24", false, false, "Ch. 21–24"},
+		{"", "Extra", false, false, "Ch. Extra"},
+		{"3", "", false, true, "Vol. 3"},
+		{"", "", true, false, "Oneshot"},
+	}
+
+	for _, tt := range tests {
+		n := ParseChapterNumber(tt.volume, tt.chapter)
+		if n.IsOneshot() != tt.oneshot {
+			t.Errorf("ParseChapterNumber(%q, %q).IsOneshot() = %t, want %t", tt.volume, tt.chapter, n.IsOneshot(), tt.oneshot)
+		}
+		if n.IsVolumeOnly() != tt.volumeOnly {
+			t.Errorf("ParseChapterNumber(%q, %q).IsVolumeOnly() = %t, want %t", tt.volume, tt.chapter, n.IsVolumeOnly(), tt.volumeOnly)
+		}
+		if got := n.Label(); got != tt.label {
+			t.Errorf("ParseChapterNumber(%q, %q).Label() = %q, want %q", tt.volume, tt.chapter, got, tt.label)
+		}
+	}
+}
+
+func TestChapterNumberCompare(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b [2]string // Volume and chapter
+		want int
+	}{
+		{"numeric order", [2]string{"", "9"}, [2]string{"", "10"}, -1},
+		{"decimal between chapters", [2]string{"", "10.5"}, [2]string{"", "11"}, -1},
+		{"decimal after chapter", [2]string{"", "10.5"}, [2]string{"", "10"}, 1},
+		{"equal", [2]string{"1", "10"}, [2]string{"1", "10"}, 0},
+		{"range after its start", [2]string{"", "21-24"}, [2]string{"", "21"}, 1},
+		{"text after numbers", [2]string{"", "Extra"}, [2]string{"", "100"}, 1},
+		{"text chapters by name", [2]string{"", "Extra"}, [2]string{"", "Omake"}, -1},
+		{"volume-only after chapters", [2]string{"2", ""}, [2]string{"2", "Extra"}, 1},
+		{"oneshot last", [2]string{"", ""}, [2]string{"", "Extra"}, 1},
+		{"volume order over chapter order", [2]string{"1", "50"}, [2]string{"2", "1"}, -1},
+		{"numeric volume order", [2]string{"9", "1"}, [2]string{"10", "1"}, -1},
+		{"numbered volume before no volume", [2]string{"5", "100"}, [2]string{"", "1"}, -1},
+		{"numbered volume before text volume", [2]string{"5", "1"}, [2]string{"Special", "1"}, -1},
+		{"text volume before no volume", [2]string{"Special", "1"}, [2]string{"", "1"}, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := ParseChapterNumber(tt.a[0], tt.a[1])
+			b := ParseChapterNumber(tt.b[0], tt.b[1])
+			if got := a.Compare(b); got != tt.want {
+				t.Errorf("Compare(%v, %v) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := b.Compare(a); got != -tt.want {
+				t.Errorf("Compare(%v, %v) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupByVolumeLabel(t *testing.T) {
+	chapters := []Chapter{
+		{Volume: "", Chapter: "101"},
+		{Volume: "3", Chapter: "23"},
+		{Volume: "3", Chapter: "21"},
+		{Volume: "3", Chapter: "22"},
+		{Volume: "3", Chapter: "26"},
+		{Volume: "", Chapter: "102"},
+		{Volume: "", Chapter: "102"},
+	}
+
+	groups := GroupByVolume(chapters)
+	want := []string{"Vol. 3: Ch. 21–23, 26", "Ch. 101–102"}
+	if len(groups) != len(want) {
+		t.Fatalf("GroupByVolume returned %d groups, want %d", len(groups), len(want))
+	}
+	for i, group := range groups {
+		if got := group.Label(); got != want[i] {
+			t.Errorf("group %d label = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestVolumeGroupLabelRuns(t *testing.T) {
+	tests := []struct {
+		chapters []string
+		want     string
+	}{
+		{[]string{"10", "11", "12"}, "Ch. 10–12"},
+		{[]string{"10", "12"}, "Ch. 10, 12"},
+		{[]string{"10", "10.5"}, "Ch. 10, 10.5"},
+		{[]string{"9.5", "10"}, "Ch. 9.5, 10"},
+		{[]string{"10", "10.5", "11"}, "Ch. 10, 10.5, 11"},
+		{[]string{"10.1", "10.2"}, "Ch. 10.1, 10.2"},
+		{[]string{"21-24", "25"}, "Ch. 21–25"},
+		{[]string{"20.5-21"}, "Ch. 20.5–21"},
+		{[]string{"10", "10", "11"}, "Ch. 10–11"},
+		{[]string{"5", "Extra"}, "Ch. 5, Extra"},
+	}
+
+	for _, tt := range tests {
+		chapters := make([]Chapter, 0, len(tt.chapters))
+		for _, chapter := range tt.chapters {
+			chapters = append(chapters, Chapter{Chapter: chapter})
+		}
+		groups := GroupByVolume(chapters)
+		if len(groups) != 1 {
+			t.Fatalf("GroupByVolume(%q) returned %d groups, want 1", tt.chapters, len(groups))
+		}
+		if got := groups[0].Label(); got != tt.want {
+			t.Errorf("label of %q = %q, want %q", tt.chapters, got, tt.want)
+		}
+	}
+}
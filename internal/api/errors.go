@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError describes a single failed request attempt
+type APIError struct {
+	Method     string
+	URL        string
+	Attempt    int           // 1-based attempt number
+	StatusCode int           // 0 if no response was received
+	Body       string        // Response body, if any
+	RetryAfter time.Duration // Server-requested wait before retrying, if any
+	Err        error         // Underlying transport error, if any
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s %s failed (attempt %d): %v", e.Method, e.URL, e.Attempt, e.Err)
+	}
+	return fmt.Sprintf("%s %s failed with status code %d (attempt %d): %s", e.Method, e.URL, e.StatusCode, e.Attempt, e.Body)
+}
+
+// Unwrap returns the underlying transport error
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the request may succeed if attempted again:
+// rate limiting, server errors and timeouts are retryable
+func (e *APIError) Retryable() bool {
+	if e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500 {
+		return true
+	}
+
+	var netErr net.Error
+	return e.StatusCode == 0 && errors.As(e.Err, &netErr) && netErr.Timeout()
+}
+
+// RetryError is returned when a request still failed after being retried.
+// It keeps every attempt; errors.As finds the last one.
+type RetryError struct {
+	Attempts []*APIError
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("giving up after %d attempts: %v", len(e.Attempts), e.Last())
+}
+
+// Unwrap returns the last attempt's error
+func (e *RetryError) Unwrap() error {
+	return e.Last()
+}
+
+// Last returns the final failed attempt
+func (e *RetryError) Last() *APIError {
+	return e.Attempts[len(e.Attempts)-1]
+}
+
+// parseRetryAfter reads the server-requested wait from a response. MangaDex
+// sends X-RateLimit-Retry-After as a Unix timestamp; the standard Retry-After
+// header holds either seconds or an HTTP date.
+func parseRetryAfter(header http.Header) time.Duration {
+	if value := strings.TrimSpace(header.Get("X-RateLimit-Retry-After")); value != "" {
+		if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return time.Until(time.Unix(unix, 0))
+		}
+	}
+
+	if value := strings.TrimSpace(header.Get("Retry-After")); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return time.Until(date)
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// followsPageSize is the largest page size MangaDex allows for the follows list
+const followsPageSize = 100
+
+// IsAuthenticated reports whether the client has a session token
+func (client *MangaDexClient) IsAuthenticated() bool {
+	return client.SessionToken != ""
+}
+
+// GetFollowedManga gets every manga followed by the logged-in user
+func (client *MangaDexClient) GetFollowedManga() ([]*Manga, error) {
+	return client.GetFollowedMangaContext(context.Background())
+}
+
+// GetFollowedMangaContext is like GetFollowedManga but can be cancelled
+// through the context
+func (client *MangaDexClient) GetFollowedMangaContext(ctx context.Context) ([]*Manga, error) {
+	return client.getFollowedManga(ctx, client.makeRequest)
+}
+
+// RefreshFollowedManga is like GetFollowedManga but never uses a cached
+// follow list without asking the server, for callers that act on follows
+// and unfollows right away
+func (client *MangaDexClient) RefreshFollowedManga(ctx context.Context) ([]*Manga, error) {
+	return client.getFollowedManga(ctx, client.makeFreshRequest)
+}
+
+// getFollowedManga reads every page of the follow list with makeRequest or
+// makeFreshRequest
+func (client *MangaDexClient) getFollowedManga(ctx context.Context, request func(context.Context, string, string, url.Values) ([]byte, error)) ([]*Manga, error) {
+	if !client.IsAuthenticated() {
+		return nil, fmt.Errorf("not authenticated")
+	}
+
+	mangas := make([]*Manga, 0)
+	for offset := 0; ; {
+		params := url.Values{
+			"limit":      {strconv.Itoa(followsPageSize)},
+			"offset":     {strconv.Itoa(offset)},
+			"includes[]": {"cover_art"},
+		}
+
+		body, err := request(ctx, http.MethodGet, "/user/follows/manga", params)
+		if err != nil {
+			return nil, err
+		}
+
+		var response struct {
+			Result string     `json:"result"`
+			Data   []MangaDTO `json:"data"`
+			CollectionDTO
+		}
+
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("failed to parse followed manga response: %w", err)
+		}
+
+		for i := range response.Data {
+			mangas = append(mangas, client.toManga(&response.Data[i]))
+		}
+
+		offset = response.Offset + len(response.Data)
+		if len(response.Data) == 0 || offset >= response.Total {
+			break
+		}
+	}
+
+	return mangas, nil
+}
+
+// GetFollowedFeed gets new chapters of every manga followed by the logged-in
+// user, optionally since a specific time and limited to the given languages
+// and content ratings. Like GetMangaChapters, chapters are returned oldest
+// first and the result is capped at MaxChapters. The manga is included so
+// each chapter carries its content rating.
+func (client *MangaDexClient) GetFollowedFeed(since time.Time, languages, contentRatings []string) ([]Chapter, error) {
+	return client.GetFollowedFeedContext(context.Background(), since, languages, contentRatings)
+}
+
+// GetFollowedFeedContext is like GetFollowedFeed but can be cancelled through
+// the context
+func (client *MangaDexClient) GetFollowedFeedContext(ctx context.Context, since time.Time, languages, contentRatings []string) ([]Chapter, error) {
+	if !client.IsAuthenticated() {
+		return nil, fmt.Errorf("not authenticated")
+	}
+
+	params := url.Values{
+		"order[createdAt]": {"asc"},
+		"contentRating[]":  contentRatingValues(contentRatings),
+		"includes[]":       {"manga"},
+	}
+	for _, lang := range languages {
+		params.Add("translatedLanguage[]", lang)
+	}
+
+	if !since.IsZero() {
+		params.Set("createdAtSince", since.Format(time.RFC3339))
+	}
+
+	return client.getChapterPages(ctx, "/user/follows/manga/feed", params)
+}
@@ -2,33 +2,89 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"math/rand"
 	"net/http"
 	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// ErrChapterLimitReached is returned alongside a partial chapter list when a
+// manga has more matching chapters than the client's MaxChapters allows
+var ErrChapterLimitReached = errors.New("chapter limit reached")
+
+const (
+	// chapterPageSize is the largest page size MangaDex allows for the chapter list
+	chapterPageSize = 100
+
+	// DefaultMaxChapters is the default cap on chapters fetched in a single call
+	DefaultMaxChapters = 500
+
+	// DefaultMaxRetries is the default number of retries for a failed request
+	DefaultMaxRetries = 3
+
+	// DefaultUploadsURL is the MangaDex host serving cover images
+	DefaultUploadsURL = "https://uploads.mangadex.org"
+
+	retryBaseDelay = 1 * time.Second
+	retryMaxDelay  = 30 * time.Second
+)
+
 // MangaDexClient handles API communication with MangaDex
 type MangaDexClient struct {
 	BaseURL      string
 	SessionToken string
 	RefreshToken string
 	TokenExpiry  time.Time
+	MaxChapters  int // Maximum chapters GetMangaChapters pages through; 0 means no limit
+	MaxRetries   int // Retries for rate limited, failed or timed out requests
+	UploadsURL   string // Host serving cover images
+	Cache        *ResponseCache // Caches GET responses; nil to always ask the server
 	httpClient   *http.Client
+	limiter      *RateLimiter
+	authMu       sync.Mutex // Lets one of several concurrent requests refresh the session
+	requests     int64      // Requests sent to the server, read atomically
 }
 
 // NewMangaDexClient creates a new MangaDex API client
 func NewMangaDexClient(baseURL string) *MangaDexClient {
 	return &MangaDexClient{
-		BaseURL:    baseURL,
-		httpClient: &http.Client{Timeout: 10 * time.Second},
+		BaseURL:     baseURL,
+		MaxChapters: DefaultMaxChapters,
+		MaxRetries:  DefaultMaxRetries,
+		UploadsURL:  DefaultUploadsURL,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		limiter:     NewRateLimiter(DefaultRateLimit, DefaultRateLimit),
 	}
 }
 
+// SetRateLimit replaces the client's rate limiter; a rate of 0 disables limiting
+func (client *MangaDexClient) SetRateLimit(requestsPerSecond float64) {
+	burst := int(requestsPerSecond)
+	client.limiter = NewRateLimiter(requestsPerSecond, burst)
+}
+
+// RequestCount returns how many requests the client has sent to the server,
+// including retries and revalidations but not responses served from the cache
+func (client *MangaDexClient) RequestCount() int64 {
+	return atomic.LoadInt64(&client.requests)
+}
+
 // Login authenticates with MangaDex API
 func (client *MangaDexClient) Login(username, password string) error {
+	return client.LoginContext(context.Background(), username, password)
+}
+
+// LoginContext is like Login but can be cancelled through the context
+func (client *MangaDexClient) LoginContext(ctx context.Context, username, password string) error {
 	// Prepare login data
 	loginData := map[string]string{
 		"username": username,
@@ -41,11 +97,7 @@ func (client *MangaDexClient) Login(username, password string) error {
 	}
 	
 	// Make auth request
-	resp, err := client.httpClient.Post(
-		fmt.Sprintf("%s/auth/login", client.BaseURL),
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	resp, err := client.postJSON(ctx, "/auth/login", jsonData)
 	
 	if err != nil {
 		return fmt.Errorf("login request failed: %w", err)
@@ -86,6 +138,12 @@ func (client *MangaDexClient) Login(username, password string) error {
 
 // RefreshToken refreshes the authentication token
 func (client *MangaDexClient) RefreshClientToken() error {
+	return client.RefreshClientTokenContext(context.Background())
+}
+
+// RefreshClientTokenContext is like RefreshClientToken but can be cancelled
+// through the context
+func (client *MangaDexClient) RefreshClientTokenContext(ctx context.Context) error {
 	// Prepare refresh token request
 	refreshData := map[string]string{
 		"token": client.RefreshToken,
@@ -97,11 +155,7 @@ func (client *MangaDexClient) RefreshClientToken() error {
 	}
 	
 	// Make refresh request
-	resp, err := client.httpClient.Post(
-		fmt.Sprintf("%s/auth/refresh", client.BaseURL),
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	resp, err := client.postJSON(ctx, "/auth/refresh", jsonData)
 	
 	if err != nil {
 		return fmt.Errorf("refresh token request failed: %w", err)
@@ -140,21 +194,66 @@ func (client *MangaDexClient) RefreshClientToken() error {
 	return nil
 }
 
+// postJSON posts a JSON body to an auth endpoint
+func (client *MangaDexClient) postJSON(ctx context.Context, endpoint string, data []byte) (*http.Response, error) {
+	if err := client.limiter.WaitContext(ctx); err != nil {
+		return nil, err
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, client.BaseURL+endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	
+	atomic.AddInt64(&client.requests, 1)
+	return client.httpClient.Do(req)
+}
+
 // ensureValidToken makes sure the session token is valid
-func (client *MangaDexClient) ensureValidToken() error {
+func (client *MangaDexClient) ensureValidToken(ctx context.Context) error {
 	if client.SessionToken == "" {
 		return fmt.Errorf("not authenticated")
 	}
 	
 	if time.Now().After(client.TokenExpiry) {
-		return client.RefreshClientToken()
+		return client.RefreshClientTokenContext(ctx)
 	}
 	
 	return nil
 }
 
-// makeRequest makes an authenticated request to the MangaDex API
-func (client *MangaDexClient) makeRequest(method, endpoint string, queryParams map[string]string) ([]byte, error) {
+// sessionToken returns a valid session token, refreshing it if it expired,
+// or an empty token if the client is not logged in
+func (client *MangaDexClient) sessionToken(ctx context.Context) (string, error) {
+	client.authMu.Lock()
+	defer client.authMu.Unlock()
+	
+	if client.SessionToken == "" {
+		return "", nil
+	}
+	if err := client.ensureValidToken(ctx); err != nil {
+		return "", err
+	}
+	return client.SessionToken, nil
+}
+
+// makeRequest makes an authenticated request to the MangaDex API, retrying
+// rate limited, failed and timed out requests with exponential backoff. GET
+// responses are served from and stored in the cache, if there is one. Once
+// the context is done, the request is abandoned and no longer retried.
+func (client *MangaDexClient) makeRequest(ctx context.Context, method, endpoint string, queryParams url.Values) ([]byte, error) {
+	return client.request(ctx, method, endpoint, queryParams, false)
+}
+
+// makeFreshRequest is like makeRequest but always asks the server, so a
+// cached response is revalidated even if it is still fresh
+func (client *MangaDexClient) makeFreshRequest(ctx context.Context, method, endpoint string, queryParams url.Values) ([]byte, error) {
+	return client.request(ctx, method, endpoint, queryParams, true)
+}
+
+// request implements makeRequest and makeFreshRequest
+func (client *MangaDexClient) request(ctx context.Context, method, endpoint string, queryParams url.Values, revalidate bool) ([]byte, error) {
 	// Build URL with query parameters
 	reqURL, err := url.Parse(fmt.Sprintf("%s%s", client.BaseURL, endpoint))
 	if err != nil {
@@ -164,61 +263,175 @@ func (client *MangaDexClient) makeRequest(method, endpoint string, queryParams m
 	// Add query parameters if provided
 	if queryParams != nil {
 		q := reqURL.Query()
-		for key, value := range queryParams {
-			q.Add(key, value)
+		for key, values := range queryParams {
+			for _, value := range values {
+				q.Add(key, value)
+			}
 		}
 		reqURL.RawQuery = q.Encode()
 	}
 	
+	// Fresh cached responses need no request; stale ones are revalidated
+	var cached *CacheEntry
+	endpointType := EndpointType(endpoint)
+	useCache := client.Cache != nil && method == http.MethodGet && client.Cache.TTL(endpointType) > 0
+	if useCache {
+		cached = client.Cache.Get(reqURL.String())
+		if cached != nil && !revalidate && client.Cache.Fresh(cached) {
+			return cached.Body, nil
+		}
+	}
+	
+	var attempts []*APIError
+	for attempt := 1; ; attempt++ {
+		body, header, err := client.doRequest(ctx, method, reqURL.String(), attempt, cached)
+		if err == nil {
+			if useCache {
+				client.storeResponse(reqURL.String(), endpointType, body, header, cached)
+			}
+			return body, nil
+		}
+		
+		// Errors before the request was sent are not retried
+		apiErr, ok := err.(*APIError)
+		if !ok {
+			return nil, err
+		}
+		
+		attempts = append(attempts, apiErr)
+		if !apiErr.Retryable() || attempt > client.MaxRetries || ctx.Err() != nil {
+			if len(attempts) == 1 {
+				return nil, apiErr
+			}
+			return nil, &RetryError{Attempts: attempts}
+		}
+		
+		timer := time.NewTimer(client.backoff(attempt, apiErr.RetryAfter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, &RetryError{Attempts: attempts}
+		case <-timer.C:
+		}
+	}
+}
+
+// doRequest performs a single request attempt. With a cached entry the
+// request is conditional, and the cached body is returned if the server
+// answers 304 Not Modified.
+func (client *MangaDexClient) doRequest(ctx context.Context, method, reqURL string, attempt int, cached *CacheEntry) ([]byte, http.Header, error) {
 	// Create request
-	req, err := http.NewRequest(method, reqURL.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	
 	// Add authentication header if we have a token
-	if client.SessionToken != "" {
-		if err := client.ensureValidToken(); err != nil {
-			return nil, fmt.Errorf("failed to ensure valid token: %w", err)
-		}
-		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", client.SessionToken))
+	token, err := client.sessionToken(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to ensure valid token: %w", err)
+	}
+	if token != "" {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+	if cached != nil {
+		setConditionalHeaders(req, cached)
 	}
 	
+	apiErr := &APIError{Method: method, URL: reqURL, Attempt: attempt}
+	
 	// Make request
+	if err := client.limiter.WaitContext(ctx); err != nil {
+		return nil, nil, err
+	}
+	atomic.AddInt64(&client.requests, 1)
 	resp, err := client.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		apiErr.Err = err
+		return nil, nil, apiErr
 	}
 	defer resp.Body.Close()
 	
 	// Read response
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		apiErr.Err = fmt.Errorf("failed to read response: %w", err)
+		return nil, nil, apiErr
+	}
+	
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return cached.Body, resp.Header, nil
 	}
 	
 	// Check for error status codes
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("request failed with status code %d: %s", resp.StatusCode, string(body))
+		apiErr.StatusCode = resp.StatusCode
+		apiErr.Body = string(body)
+		apiErr.RetryAfter = parseRetryAfter(resp.Header)
+		return nil, nil, apiErr
 	}
 	
-	return body, nil
+	return body, resp.Header, nil
+}
+
+// storeResponse caches a response body. A 304 response may leave out the
+// validators, in which case the previous entry's are kept.
+func (client *MangaDexClient) storeResponse(reqURL, endpointType string, body []byte, header http.Header, previous *CacheEntry) {
+	entry := &CacheEntry{
+		URL:          reqURL,
+		Type:         endpointType,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		StoredAt:     time.Now(),
+		Body:         body,
+	}
+	if previous != nil && entry.ETag == "" && entry.LastModified == "" {
+		entry.ETag = previous.ETag
+		entry.LastModified = previous.LastModified
+	}
+	
+	// The response is still good if it cannot be cached
+	if err := client.Cache.Put(entry); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// backoff returns how long to wait before the next attempt: exponential
+// backoff with jitter, but never less than the server asked for
+func (client *MangaDexClient) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	delay := retryBaseDelay << uint(attempt-1)
+	if delay <= 0 || delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	
+	// Random jitter in [delay/2, delay) spreads out concurrent retries
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)))
+	
+	if retryAfter > delay {
+		return retryAfter
+	}
+	return delay
 }
 
 // GetManga gets details for a specific manga by ID
 func (client *MangaDexClient) GetManga(id string) (*Manga, error) {
-	body, err := client.makeRequest(http.MethodGet, fmt.Sprintf("/manga/%s", id), nil)
+	return client.GetMangaContext(context.Background(), id)
+}
+
+// GetMangaContext is like GetManga but can be cancelled through the context
+func (client *MangaDexClient) GetMangaContext(ctx context.Context, id string) (*Manga, error) {
+	params := url.Values{
+		"includes[]": {"cover_art"},
+	}
+	
+	body, err := client.makeRequest(ctx, http.MethodGet, fmt.Sprintf("/manga/%s", id), params)
 	if err != nil {
 		return nil, err
 	}
 	
 	var response struct {
-		Result string `json:"result"`
-		Data   struct {
-			ID         string               `json:"id"`
-			Attributes MangaAttributesDTO   `json:"attributes"`
-			Relationships []RelationshipDTO `json:"relationships"`
-		} `json:"data"`
+		Result string   `json:"result"`
+		Data   MangaDTO `json:"data"`
 	}
 	
 	if err := json.Unmarshal(body, &response); err != nil {
@@ -226,50 +439,31 @@ func (client *MangaDexClient) GetManga(id string) (*Manga, error) {
 	}
 	
 	// Convert API response to our Manga model
-	manga := &Manga{
-		ID:          response.Data.ID,
-		Title:       response.Data.Attributes.Title,
-		Description: response.Data.Attributes.Description,
-		Status:      response.Data.Attributes.Status,
-		CreatedAt:   response.Data.Attributes.CreatedAt,
-		UpdatedAt:   response.Data.Attributes.UpdatedAt,
-	}
-	
-	// Extract cover art URL if available
-	for _, rel := range response.Data.Relationships {
-		if rel.Type == "cover_art" {
-			manga.CoverArtID = rel.ID
-			break
-		}
-	}
-	
-	if manga.CoverArtID != "" {
-		manga.CoverArtURL = fmt.Sprintf("%s/covers/%s/%s.256.jpg", client.BaseURL, manga.ID, manga.CoverArtID)
-	}
-	
-	return manga, nil
+	return client.toManga(&response.Data), nil
 }
 
 // SearchManga searches for manga by title
 func (client *MangaDexClient) SearchManga(title string) ([]*Manga, error) {
-	params := map[string]string{
-		"title": title,
-		"limit": "5",
-		"order[relevance]": "desc",
+	return client.SearchMangaContext(context.Background(), title)
+}
+
+// SearchMangaContext is like SearchManga but can be cancelled through the context
+func (client *MangaDexClient) SearchMangaContext(ctx context.Context, title string) ([]*Manga, error) {
+	params := url.Values{
+		"title": {title},
+		"limit": {"5"},
+		"order[relevance]": {"desc"},
+		"includes[]": {"cover_art"},
 	}
 	
-	body, err := client.makeRequest(http.MethodGet, "/manga", params)
+	body, err := client.makeRequest(ctx, http.MethodGet, "/manga", params)
 	if err != nil {
 		return nil, err
 	}
 	
 	var response struct {
-		Result string `json:"result"`
-		Data   []struct {
-			ID         string               `json:"id"`
-			Attributes MangaAttributesDTO   `json:"attributes"`
-			Relationships []RelationshipDTO `json:"relationships"`
-		} `json:"data"`
+		Result string     `json:"result"`
+		Data   []MangaDTO `json:"data"`
 	}
 	
 	if err := json.Unmarshal(body, &response); err != nil {
@@ -278,90 +472,104 @@ func (client *MangaDexClient) SearchManga(title string) ([]*Manga, error) {
 	
 	// Convert API response to our Manga model
 	mangas := make([]*Manga, 0, len(response.Data))
-	for _, data := range response.Data {
-		manga := &Manga{
-			ID:          data.ID,
-			Title:       data.Attributes.Title,
-			Description: data.Attributes.Description,
-			Status:      data.Attributes.Status,
-			CreatedAt:   data.Attributes.CreatedAt,
-			UpdatedAt:   data.Attributes.UpdatedAt,
-		}
-		
-		// Extract cover art URL if available
-		for _, rel := range data.Relationships {
-			if rel.Type == "cover_art" {
-				manga.CoverArtID = rel.ID
-				break
-			}
-		}
-		
-		if manga.CoverArtID != "" {
-			manga.CoverArtURL = fmt.Sprintf("%s/covers/%s/%s.256.jpg", client.BaseURL, manga.ID, manga.CoverArtID)
-		}
-		
-		mangas = append(mangas, manga)
+	for i := range response.Data {
+		mangas = append(mangas, client.toManga(&response.Data[i]))
 	}
 	
 	return mangas, nil
 }
 
+// toManga converts a manga entity and links its cover thumbnail, if the
+// cover_art relationship was included
+func (client *MangaDexClient) toManga(dto *MangaDTO) *Manga {
+	manga := dto.ToManga()
+	if manga.CoverFileName != "" {
+		manga.CoverArtURL = fmt.Sprintf("%s/covers/%s/%s.256.jpg", client.UploadsURL, manga.ID, manga.CoverFileName)
+	}
+	return manga
+}
+
 // GetMangaChapters gets chapters for a manga, optionally since a specific time
-func (client *MangaDexClient) GetMangaChapters(mangaID string, since time.Time) ([]Chapter, error) {
-	params := map[string]string{
-		"manga":              mangaID,
-		"order[publishAt]":   "desc",
-		"limit":              "50",
-		"contentRating[]":    "safe",
+// and limited to the given languages (all if none are given), if the manga has
+// one of the given content ratings (DefaultContentRatings if none are given). Chapters are returned oldest first. It follows the
+// collection's offset and total until every chapter has been read; if
+// MaxChapters is reached first, the chapters read so far are returned together
+// with ErrChapterLimitReached.
+func (client *MangaDexClient) GetMangaChapters(mangaID string, since time.Time, languages, contentRatings []string) ([]Chapter, error) {
+	return client.GetMangaChaptersContext(context.Background(), mangaID, since, languages, contentRatings)
+}
+
+// GetMangaChaptersContext is like GetMangaChapters but can be cancelled
+// through the context
+func (client *MangaDexClient) GetMangaChaptersContext(ctx context.Context, mangaID string, since time.Time, languages, contentRatings []string) ([]Chapter, error) {
+	params := url.Values{
+		"manga":              {mangaID},
+		"order[createdAt]":   {"asc"},
+		"contentRating[]":    contentRatingValues(contentRatings),
+	}
+	for _, lang := range languages {
+		params.Add("translatedLanguage[]", lang)
 	}
 	
 	// Add "createdAt" filter if "since" is not zero time
 	if !since.IsZero() {
-		params["createdAtSince"] = since.Format(time.RFC3339)
-	}
-	
-	body, err := client.makeRequest(http.MethodGet, "/chapter", params)
-	if err != nil {
-		return nil, err
+		params.Set("createdAtSince", since.Format(time.RFC3339))
 	}
 	
-	var response struct {
-		Result string `json:"result"`
-		Data   []struct {
-			ID         string                `json:"id"`
-			Attributes ChapterAttributesDTO  `json:"attributes"`
-			Relationships []RelationshipDTO  `json:"relationships"`
-		} `json:"data"`
+	return client.getChapterPages(ctx, "/chapter", params)
+}
+
+// contentRatingValues returns the contentRating[] query values for the given
+// ratings, falling back to DefaultContentRatings
+func contentRatingValues(contentRatings []string) []string {
+	if len(contentRatings) == 0 {
+		return DefaultContentRatings
 	}
-	
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to parse chapter response: %w", err)
-	}
-	
-	// Convert API response to our Chapter model
-	chapters := make([]Chapter, 0, len(response.Data))
-	for _, data := range response.Data {
-		chapter := Chapter{
-			ID:                data.ID,
-			Title:             data.Attributes.Title,
-			Volume:            data.Attributes.Volume,
-			Chapter:           data.Attributes.Chapter,
-			TranslatedLanguage: data.Attributes.TranslatedLanguage,
-			PublishAt:         data.Attributes.PublishAt,
-			CreatedAt:         data.Attributes.CreatedAt,
-			UpdatedAt:         data.Attributes.UpdatedAt,
+	return contentRatings
+}
+
+// getChapterPages reads every page of a chapter collection endpoint, up to
+// MaxChapters. Scanlation groups are included so their names are known.
+func (client *MangaDexClient) getChapterPages(ctx context.Context, endpoint string, params url.Values) ([]Chapter, error) {
+	params.Add("includes[]", "scanlation_group")
+	
+	chapters := make([]Chapter, 0)
+	for offset := 0; ; {
+		limit := chapterPageSize
+		if client.MaxChapters > 0 && client.MaxChapters-len(chapters) < limit {
+			limit = client.MaxChapters - len(chapters)
 		}
+		params.Set("limit", strconv.Itoa(limit))
+		params.Set("offset", strconv.Itoa(offset))
 		
-		// Extract scanlation groups
-		groups := make([]string, 0)
-		for _, rel := range data.Relationships {
-			if rel.Type == "scanlation_group" {
-				groups = append(groups, rel.ID)
-			}
+		body, err := client.makeRequest(ctx, http.MethodGet, endpoint, params)
+		if err != nil {
+			return nil, err
+		}
+		
+		var response struct {
+			Result string       `json:"result"`
+			Data   []ChapterDTO `json:"data"`
+			CollectionDTO
+		}
+		
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("failed to parse chapter response: %w", err)
 		}
-		chapter.Groups = groups
 		
-		chapters = append(chapters, chapter)
+		// Convert API response to our Chapter model
+		for _, data := range response.Data {
+			chapters = append(chapters, data.ToChapter())
+		}
+		
+		// Stop when the collection is exhausted or the cap is reached
+		offset = response.Offset + len(response.Data)
+		if len(response.Data) == 0 || offset >= response.Total {
+			break
+		}
+		if client.MaxChapters > 0 && len(chapters) >= client.MaxChapters {
+			return chapters, fmt.Errorf("%w: read %d of %d chapters", ErrChapterLimitReached, len(chapters), response.Total)
+		}
 	}
 	
 	return chapters, nil
@@ -369,43 +577,30 @@ func (client *MangaDexClient) GetMangaChapters(mangaID string, since time.Time)
 
 // GetChapterDetails gets detailed information for a chapter
 func (client *MangaDexClient) GetChapterDetails(chapterID string) (*Chapter, error) {
-	body, err := client.makeRequest(http.MethodGet, fmt.Sprintf("/chapter/%s", chapterID), nil)
+	return client.GetChapterDetailsContext(context.Background(), chapterID)
+}
+
+// GetChapterDetailsContext is like GetChapterDetails but can be cancelled
+// through the context
+func (client *MangaDexClient) GetChapterDetailsContext(ctx context.Context, chapterID string) (*Chapter, error) {
+	params := url.Values{
+		"includes[]": {"scanlation_group"},
+	}
+	
+	body, err := client.makeRequest(ctx, http.MethodGet, fmt.Sprintf("/chapter/%s", chapterID), params)
 	if err != nil {
 		return nil, err
 	}
 	
 	var response struct {
-		Result string `json:"result"`
-		Data   struct {
-			ID         string               `json:"id"`
-			Attributes ChapterAttributesDTO `json:"attributes"`
-			Relationships []RelationshipDTO `json:"relationships"`
-		} `json:"data"`
+		Result string     `json:"result"`
+		Data   ChapterDTO `json:"data"`
 	}
 	
 	if err := json.Unmarshal(body, &response); err != nil {
 		return nil, fmt.Errorf("failed to parse chapter details response: %w", err)
 	}
 	
-	chapter := &Chapter{
-		ID:                response.Data.ID,
-		Title:             response.Data.Attributes.Title,
-		Volume:            response.Data.Attributes.Volume,
-		Chapter:           response.Data.Attributes.Chapter,
-		TranslatedLanguage: response.Data.Attributes.TranslatedLanguage,
-		PublishAt:         response.Data.Attributes.PublishAt,
-		CreatedAt:         response.Data.Attributes.CreatedAt,
-		UpdatedAt:         response.Data.Attributes.UpdatedAt,
-	}
-	
-	// Extract scanlation groups
-	groups := make([]string, 0)
-	for _, rel := range response.Data.Relationships {
-		if rel.Type == "scanlation_group" {
-			groups = append(groups, rel.ID)
-		}
-	}
-	chapter.Groups = groups
-	
-	return chapter, nil
+	chapter := response.Data.ToChapter()
+	return &chapter, nil
 }
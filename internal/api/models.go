@@ -10,21 +10,40 @@ type Manga struct {
 	Title       map[string]string  `json:"title"`
 	Description map[string]string  `json:"description"`
 	CoverArtID  string             `json:"cover_art_id"`
-	CoverArtURL string             `json:"cover_art_url"`
+	CoverFileName string           `json:"cover_file_name"` // Known if the cover_art relationship was included
+	CoverArtURL string             `json:"cover_art_url"`   // 256px thumbnail on the uploads server
 	Tags        []string           `json:"tags"`
 	Status      string             `json:"status"`
+	ContentRating string           `json:"content_rating"`
 	CreatedAt   time.Time          `json:"created_at"`
 	UpdatedAt   time.Time          `json:"updated_at"`
 }
 
+// Content ratings
+const (
+	ContentRatingSafe         = "safe"
+	ContentRatingSuggestive   = "suggestive"
+	ContentRatingErotica      = "erotica"
+	ContentRatingPornographic = "pornographic"
+)
+
+// ContentRatings lists every content rating
+var ContentRatings = []string{ContentRatingSafe, ContentRatingSuggestive, ContentRatingErotica, ContentRatingPornographic}
+
+// DefaultContentRatings are the ratings MangaDex itself returns when none are requested
+var DefaultContentRatings = []string{ContentRatingSafe, ContentRatingSuggestive, ContentRatingErotica}
+
 // Chapter represents a manga chapter
 type Chapter struct {
 	ID                string    `json:"id"`
+	MangaID           string    `json:"manga_id"`
 	Title             string    `json:"title"`
 	Volume            string    `json:"volume"`
 	Chapter           string    `json:"chapter"`
 	TranslatedLanguage string    `json:"translated_language"`
-	Groups            []string  `json:"groups"`
+	Groups            []string  `json:"groups"`      // Scanlation group IDs
+	GroupNames        []string  `json:"group_names"` // Scanlation group names, aligned with Groups
+	ContentRating     string    `json:"content_rating"` // Rating of the manga, if included in the response
 	PublishAt         time.Time `json:"publish_at"`
 	CreatedAt         time.Time `json:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at"`
@@ -35,6 +54,7 @@ type MangaAttributesDTO struct {
 	Title       map[string]string     `json:"title"`
 	Description map[string]string     `json:"description"`
 	Status      string                `json:"status"`
+	ContentRating string              `json:"contentRating"`
 	CreatedAt   time.Time             `json:"createdAt"`
 	UpdatedAt   time.Time             `json:"updatedAt"`
 }
@@ -50,10 +70,108 @@ type ChapterAttributesDTO struct {
 	UpdatedAt         time.Time `json:"updatedAt"`
 }
 
-// RelationshipDTO represents a relationship in MangaDex API responses
+// MangaDTO represents a manga entity in MangaDex API responses
+type MangaDTO struct {
+	ID            string             `json:"id"`
+	Attributes    MangaAttributesDTO `json:"attributes"`
+	Relationships []RelationshipDTO  `json:"relationships"`
+}
+
+// ToManga converts the API entity to our Manga model
+func (d *MangaDTO) ToManga() *Manga {
+	manga := &Manga{
+		ID:          d.ID,
+		Title:       d.Attributes.Title,
+		Description: d.Attributes.Description,
+		Status:      d.Attributes.Status,
+		ContentRating: d.Attributes.ContentRating,
+		CreatedAt:   d.Attributes.CreatedAt,
+		UpdatedAt:   d.Attributes.UpdatedAt,
+	}
+
+	for _, rel := range d.Relationships {
+		if rel.Type == "cover_art" {
+			manga.CoverArtID = rel.ID
+			manga.CoverFileName = rel.Attributes.FileName
+			break
+		}
+	}
+
+	return manga
+}
+
+// ChapterDTO represents a chapter entity in MangaDex API responses
+type ChapterDTO struct {
+	ID            string               `json:"id"`
+	Attributes    ChapterAttributesDTO `json:"attributes"`
+	Relationships []RelationshipDTO    `json:"relationships"`
+}
+
+// ToChapter converts the API entity to our Chapter model
+func (d *ChapterDTO) ToChapter() Chapter {
+	chapter := Chapter{
+		ID:                 d.ID,
+		Title:              d.Attributes.Title,
+		Volume:             d.Attributes.Volume,
+		Chapter:            d.Attributes.Chapter,
+		TranslatedLanguage: d.Attributes.TranslatedLanguage,
+		PublishAt:          d.Attributes.PublishAt,
+		CreatedAt:          d.Attributes.CreatedAt,
+		UpdatedAt:          d.Attributes.UpdatedAt,
+	}
+
+	// Extract manga and scanlation groups
+	groups := make([]string, 0)
+	names := make([]string, 0)
+	for _, rel := range d.Relationships {
+		switch rel.Type {
+		case "manga":
+			chapter.MangaID = rel.ID
+			chapter.ContentRating = rel.Attributes.ContentRating
+		case "scanlation_group":
+			groups = append(groups, rel.ID)
+			names = append(names, rel.Attributes.Name)
+		}
+	}
+	chapter.Groups = groups
+	chapter.GroupNames = names
+
+	return chapter
+}
+
+// CollectionDTO represents the paging fields of a MangaDex collection response
+type CollectionDTO struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Total  int `json:"total"`
+}
+
+// RelationshipDTO represents a relationship in MangaDex API responses.
+// Attributes are only present for relationship types requested with includes[].
 type RelationshipDTO struct {
-	ID   string `json:"id"`
-	Type string `json:"type"`
+	ID         string                    `json:"id"`
+	Type       string                    `json:"type"`
+	Attributes RelationshipAttributesDTO `json:"attributes"`
+}
+
+// RelationshipAttributesDTO represents the attributes of an included relationship
+type RelationshipAttributesDTO struct {
+	Name          string `json:"name"`          // scanlation_group
+	ContentRating string `json:"contentRating"` // manga
+	FileName      string `json:"fileName"`      // cover_art
+}
+
+// GetGroupNames returns the names of the chapter's scanlation groups, using
+// the group ID where the name is unknown
+func (c *Chapter) GetGroupNames() []string {
+	names := make([]string, len(c.Groups))
+	for i, id := range c.Groups {
+		names[i] = id
+		if i < len(c.GroupNames) && c.GroupNames[i] != "" {
+			names[i] = c.GroupNames[i]
+		}
+	}
+	return names
 }
 
 // GetTitle returns the title in the preferred language, falling back to English or the first available
@@ -14,16 +14,80 @@ type SMTPConfig struct {
 	Port      int    `json:"port"`
 	Username  string `json:"username"`
 	Password  string `json:"password"`
-	UseTLS    bool   `json:"use_tls"`
+	UseTLS    bool   `json:"use_tls"` // Used when Security is empty: implicit TLS on port 465, STARTTLS otherwise
+	Security  string `json:"security"` // ssl, starttls or none
+	AuthMechanism string `json:"auth_mechanism"` // plain, login, cram-md5 or xoauth2; empty picks from what the server offers
+	CAFile    string `json:"ca_file"` // PEM bundle trusted in addition to the system roots
+	InsecureSkipVerify bool `json:"insecure_skip_verify"` // Skip certificate verification, for internal relays
 	FromEmail string `json:"from_email"`
 	FromName  string `json:"from_name"`
+	MaxMessagesPerConnection int     `json:"max_messages_per_connection"` // Reconnect after this many messages; 0 for no limit
+	SendRate                 float64 `json:"send_rate"`                   // Messages per second; 0 for no limit
+	OAuth     SMTPOAuthConfig `json:"oauth"` // Token settings for the xoauth2 auth mechanism
+}
+
+// SMTPOAuthConfig stores OAuth2 settings for XOAUTH2 SMTP authentication.
+// Access tokens come from the refresh token if there is one, otherwise from
+// the client credentials grant.
+type SMTPOAuthConfig struct {
+	Provider      string `json:"provider"`  // google or microsoft; sets the endpoints and scope left empty
+	TenantID      string `json:"tenant_id"` // Microsoft tenant; defaults to organizations
+	ClientID      string `json:"client_id"`
+	ClientSecret  string `json:"client_secret"`
+	RefreshToken  string `json:"refresh_token"`
+	AuthURL       string `json:"auth_url"` // Authorization endpoint for the loopback sign-in flow
+	DeviceAuthURL string `json:"device_auth_url"`
+	TokenURL      string `json:"token_url"`
+	Scope         string `json:"scope"`
+}
+
+// MailConfig selects how emails are delivered
+type MailConfig struct {
+	Transport    string `json:"transport"`     // smtp (default), sendmail, maildir or mbox
+	SendmailPath string `json:"sendmail_path"` // For sendmail; defaults to /usr/sbin/sendmail
+	Path         string `json:"path"`          // Maildir directory or mbox file
+}
+
+// UnsubscribeConfig enables unsubscribe links in emails and the handler they point to
+type UnsubscribeConfig struct {
+	BaseURL    string `json:"base_url"`    // Public URL of the handler, e.g. https://notifier.example.com/unsubscribe; empty disables links
+	ListenAddr string `json:"listen_addr"` // Address the service serves the handler on, e.g. :8080
+	Secret     string `json:"secret"`      // Token signing key; generated when links are enabled
+}
+
+// HTTPCacheConfig configures the on-disk cache of MangaDex API responses
+type HTTPCacheConfig struct {
+	Disabled bool           `json:"disabled"`
+	Dir      string         `json:"dir"` // Empty uses "http-cache" next to the database
+	TTL      map[string]int `json:"ttl"` // Endpoint type -> seconds; missing types use the defaults, 0 disables caching
+}
+
+// NotifierConfig defines a named notification channel users can pick
+type NotifierConfig struct {
+	Type       string `json:"type"` // discord, slack or webhook
+	WebhookURL string `json:"webhook_url"`
+	Secret     string `json:"secret,omitempty"` // HMAC signing key for generic webhooks
 }
 
 // Config stores the application configuration
 type Config struct {
 	DatabasePath       string     `json:"database_path"`
 	SMTPSettings       SMTPConfig `json:"smtp_settings"`
+	Mail               MailConfig `json:"mail"` // Transport for emails; SMTP unless set
+	Unsubscribe        UnsubscribeConfig `json:"unsubscribe"`
 	UpdateCheckInterval int        `json:"update_check_interval"` // in seconds
+	MaxChaptersPerCheck int        `json:"max_chapters_per_check"` // per subscription; 0 uses the client default
+	CheckWorkers       int        `json:"check_workers"` // Manga polled at once; 0 uses the engine default
+	APIRateLimit       float64    `json:"api_rate_limit"`  // requests per second; 0 uses the client default
+	APIMaxRetries      int        `json:"api_max_retries"` // 0 uses the client default
+	UseFollowFeed      bool       `json:"use_follow_feed"` // Read followed manga from the account feed when logged in
+	ContentRatings     []string   `json:"content_ratings"` // Default for subscriptions that set none; empty uses safe, suggestive and erotica
+	Notifiers          map[string]NotifierConfig `json:"notifiers"` // Channel name -> settings; "email" is built in
+	TemplateDir        string     `json:"template_dir"` // Directory with email template overrides; empty uses the defaults
+	CoverCacheDir      string     `json:"cover_cache_dir"` // Downloaded cover thumbnails; empty uses "covers" next to the database
+	HTTPCache          HTTPCacheConfig `json:"http_cache"`
+	DescriptionLength  int        `json:"description_length"` // Maximum manga description length in emails; 0 for no limit
+	OutboxMaxAttempts  int        `json:"outbox_max_attempts"` // Delivery attempts before a notification is given up; 0 uses the engine default
 	MangaDexAPIURL     string     `json:"mangadex_api_url"`
 	AuthToken          string     `json:"auth_token"`
 	RefreshToken       string     `json:"refresh_token"`
@@ -82,10 +146,21 @@ func Default() *Config {
 			Username:  "",
 			Password:  "",
 			UseTLS:    true,
+			Security:  "starttls",
 			FromEmail: "manga-updates@example.com",
 			FromName:  "MangaDex Notifier",
+			MaxMessagesPerConnection: 100,
 		},
 		UpdateCheckInterval: 3600, // 1 hour
+		MaxChaptersPerCheck: 500,
+		CheckWorkers:        4,
+		APIRateLimit:        5,
+		APIMaxRetries:       3,
+		UseFollowFeed:       true,
+		ContentRatings:      []string{"safe", "suggestive", "erotica"},
+		Notifiers:           map[string]NotifierConfig{},
+		DescriptionLength:   500,
+		OutboxMaxAttempts:   8,
 		MangaDexAPIURL:      "https://api.mangadex.org",
 		AuthToken:           "",
 		RefreshToken:        "",
@@ -7,6 +7,7 @@ import (
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 	"os"
 )
@@ -32,8 +33,17 @@ func NewDB(path string) (*DB, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// SQLite allows one writer at a time; sharing a single connection makes
+	// concurrent callers wait their turn instead of failing with "database
+	// is locked"
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
 	// Run migrations
-	if err := db.AutoMigrate(&User{}, &Subscription{}); err != nil {
+	if err := db.AutoMigrate(&User{}, &Subscription{}, &SeenChapter{}, &OutboxItem{}); err != nil {
 		return nil, fmt.Errorf("failed to run database migrations: %w", err)
 	}
 
@@ -77,6 +87,13 @@ func (db *DB) GetUserByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
+// ListUsers gets all users
+func (db *DB) ListUsers() ([]User, error) {
+	var users []User
+	result := db.conn.Order("email").Find(&users)
+	return users, result.Error
+}
+
 // UpdateUser updates a user in the database
 func (db *DB) UpdateUser(user *User) error {
 	user.UpdatedAt = time.Now()
@@ -109,10 +126,25 @@ func (db *DB) UpdateSubscription(subscription *Subscription) error {
 	return result.Error
 }
 
-// DeleteSubscription removes a subscription from the database
+// DeleteSubscription removes a subscription and its chapter ledger from the database
 func (db *DB) DeleteSubscription(id int) error {
-	result := db.conn.Delete(&Subscription{}, id)
-	return result.Error
+	return db.conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", id).Delete(&SeenChapter{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Subscription{}, id).Error
+	})
+}
+
+// GetSubscriptionsByID gets the subscriptions with the given IDs; IDs of
+// deleted subscriptions are left out
+func (db *DB) GetSubscriptionsByID(ids []int) ([]Subscription, error) {
+	var subscriptions []Subscription
+	if len(ids) == 0 {
+		return subscriptions, nil
+	}
+	result := db.conn.Where("id IN ?", ids).Find(&subscriptions)
+	return subscriptions, result.Error
 }
 
 // ListSubscriptions gets all subscriptions
@@ -122,10 +154,11 @@ func (db *DB) ListSubscriptions() ([]Subscription, error) {
 	return subscriptions, result.Error
 }
 
-// ListActiveSubscriptions gets all active subscriptions
+// ListActiveSubscriptions gets all active subscriptions of active users
 func (db *DB) ListActiveSubscriptions() ([]Subscription, error) {
 	var subscriptions []Subscription
-	result := db.conn.Where("active = ?", true).Find(&subscriptions)
+	activeUsers := db.conn.Model(&User{}).Select("id").Where("active = ?", true)
+	result := db.conn.Where("active = ? AND user_id IN (?)", true, activeUsers).Find(&subscriptions)
 	return subscriptions, result.Error
 }
 
@@ -134,4 +167,300 @@ func (db *DB) GetSubscriptionsByUserID(userID int) ([]Subscription, error) {
 	var subscriptions []Subscription
 	result := db.conn.Where("user_id = ?", userID).Find(&subscriptions)
 	return subscriptions, result.Error
-}
\ No newline at end of file
+}
+
+// Chapter ledger operations
+
+// RecordSeenChapters stores newly found chapters for a subscription and advances
+// its check time in a single transaction, and returns the chapters that were
+// not in the ledger yet. Chapters already in the ledger are left untouched, so
+// fetching overlapping time windows never duplicates entries.
+func (db *DB) RecordSeenChapters(subscription *Subscription, chapters []SeenChapter) ([]SeenChapter, error) {
+	added := make([]SeenChapter, 0, len(chapters))
+	err := db.conn.Transaction(func(tx *gorm.DB) error {
+		if len(chapters) > 0 {
+			chapterIDs := make([]string, 0, len(chapters))
+			for _, chapter := range chapters {
+				chapterIDs = append(chapterIDs, chapter.ChapterID)
+			}
+			var existing []string
+			if err := tx.Model(&SeenChapter{}).Where("subscription_id = ? AND chapter_id IN ?", subscription.ID, chapterIDs).
+				Pluck("chapter_id", &existing).Error; err != nil {
+				return err
+			}
+			recorded := make(map[string]bool, len(existing))
+			for _, chapterID := range existing {
+				recorded[chapterID] = true
+			}
+
+			for _, chapter := range chapters {
+				if !recorded[chapter.ChapterID] {
+					recorded[chapter.ChapterID] = true
+					chapter.SubscriptionID = subscription.ID
+					added = append(added, chapter)
+				}
+			}
+		}
+
+		if len(added) > 0 {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&added)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		subscription.UpdatedAt = time.Now()
+		return tx.Save(subscription).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// ListPendingChapters gets the chapters of a subscription that have not been notified yet
+func (db *DB) ListPendingChapters(subscriptionID int) ([]SeenChapter, error) {
+	var chapters []SeenChapter
+	result := db.conn.Where("subscription_id = ? AND notified = ?", subscriptionID, false).
+		Order("chapter_created_at").
+		Find(&chapters)
+	return chapters, result.Error
+}
+
+// ListReleases gets every ledger entry of a subscription with one of the given
+// release keys, oldest first, whether notified or not
+func (db *DB) ListReleases(subscriptionID int, releaseKeys []string) ([]SeenChapter, error) {
+	var chapters []SeenChapter
+	if len(releaseKeys) == 0 {
+		return chapters, nil
+	}
+
+	result := db.conn.Where("subscription_id = ? AND release_key IN ?", subscriptionID, releaseKeys).
+		Order("chapter_created_at").
+		Find(&chapters)
+	return chapters, result.Error
+}
+
+// SupersedeChapters marks chapters of a subscription as duplicates of another
+// release. They count as notified and are never delivered.
+func (db *DB) SupersedeChapters(subscriptionID int, chapterIDs []string) error {
+	if len(chapterIDs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	result := db.conn.Model(&SeenChapter{}).
+		Where("subscription_id = ? AND chapter_id IN ?", subscriptionID, chapterIDs).
+		Updates(map[string]interface{}{
+			"superseded":  true,
+			"notified":    true,
+			"notified_at": now,
+			"updated_at":  now,
+		})
+	return result.Error
+}
+
+// markDelivered adds a channel to the delivered channels of ledger entries
+func markDelivered(tx *gorm.DB, chapters []SeenChapter, channel string, allChannels []string) error {
+	now := time.Now()
+	for _, chapter := range chapters {
+		chapter.DeliveredChannels = addChannel(chapter.DeliveredChannels, channel)
+
+		notified := true
+		for _, c := range allChannels {
+			if !chapter.IsDelivered(c) {
+				notified = false
+				break
+			}
+		}
+		if notified && !chapter.Notified {
+			chapter.Notified = true
+			chapter.NotifiedAt = &now
+		}
+
+		chapter.UpdatedAt = now
+		if err := tx.Save(&chapter).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MarkChaptersDelivered marks ledger entries delivered on a channel without
+// an outbox item, e.g. when another subscriber's notification on a shared
+// channel covers them
+func (db *DB) MarkChaptersDelivered(chapters []SeenChapter, channel string, allChannels []string) error {
+	return db.conn.Transaction(func(tx *gorm.DB) error {
+		return markDelivered(tx, chapters, channel, allChannels)
+	})
+}
+
+// ChaptersOnChannel returns which of the given MangaDex chapter IDs are queued
+// or delivered on a channel for any subscription
+func (db *DB) ChaptersOnChannel(channel string, chapterIDs []string) (map[string]bool, error) {
+	on := make(map[string]bool)
+	if len(chapterIDs) == 0 {
+		return on, nil
+	}
+
+	var chapters []SeenChapter
+	if err := db.conn.Where("chapter_id IN ?", chapterIDs).Find(&chapters).Error; err != nil {
+		return nil, err
+	}
+	for _, chapter := range chapters {
+		if chapter.IsQueued(channel) || chapter.IsDelivered(channel) {
+			on[chapter.ChapterID] = true
+		}
+	}
+	return on, nil
+}
+
+// GetSeenChapters gets ledger entries by ID
+func (db *DB) GetSeenChapters(ids []int) ([]SeenChapter, error) {
+	var chapters []SeenChapter
+	if len(ids) == 0 {
+		return chapters, nil
+	}
+	result := db.conn.Where("id IN ?", ids).Order("chapter_created_at").Find(&chapters)
+	return chapters, result.Error
+}
+
+// Outbox operations
+
+// EnqueueOutboxItem adds a notification to the outbox and marks its chapters
+// queued on the item's channel, in a single transaction
+func (db *DB) EnqueueOutboxItem(item *OutboxItem) error {
+	return db.conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+
+		var chapters []SeenChapter
+		if err := tx.Where("id IN ?", item.GetChapterIDs()).Find(&chapters).Error; err != nil {
+			return err
+		}
+		for _, chapter := range chapters {
+			chapter.QueuedChannels = addChannel(chapter.QueuedChannels, item.Channel)
+			if err := tx.Save(&chapter).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetOutboxItem gets an outbox item by ID
+func (db *DB) GetOutboxItem(id int) (*OutboxItem, error) {
+	var item OutboxItem
+	result := db.conn.First(&item, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &item, nil
+}
+
+// ListOutboxItems gets outbox items, oldest first, optionally only those with a status
+func (db *DB) ListOutboxItems(status string) ([]OutboxItem, error) {
+	var items []OutboxItem
+	query := db.conn.Order("id")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	result := query.Find(&items)
+	return items, result.Error
+}
+
+// ListDueOutboxItems gets pending outbox items whose next attempt is due
+func (db *DB) ListDueOutboxItems(now time.Time) ([]OutboxItem, error) {
+	var items []OutboxItem
+	result := db.conn.Where("status = ? AND next_attempt_at <= ?", OutboxPending, now).
+		Order("id").
+		Find(&items)
+	return items, result.Error
+}
+
+// UpdateOutboxItem updates an outbox item
+func (db *DB) UpdateOutboxItem(item *OutboxItem) error {
+	item.UpdatedAt = time.Now()
+	result := db.conn.Save(item)
+	return result.Error
+}
+
+// CompleteOutboxItem marks an outbox item sent and its chapters delivered on
+// its channel, in a single transaction
+func (db *DB) CompleteOutboxItem(item *OutboxItem, allChannels []string) error {
+	return db.conn.Transaction(func(tx *gorm.DB) error {
+		var chapters []SeenChapter
+		if err := tx.Where("id IN ?", item.GetChapterIDs()).Find(&chapters).Error; err != nil {
+			return err
+		}
+		if err := markDelivered(tx, chapters, item.Channel, allChannels); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		item.Status = OutboxSent
+		item.SentAt = &now
+		item.LastError = ""
+		item.UpdatedAt = now
+		return tx.Save(item).Error
+	})
+}
+
+// RetryOutboxItems makes failed outbox items pending again with a fresh
+// attempt count. With no IDs, every failed item is retried.
+func (db *DB) RetryOutboxItems(ids []int) (int64, error) {
+	query := db.conn.Model(&OutboxItem{}).Where("status = ?", OutboxFailed)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+
+	now := time.Now()
+	result := query.Updates(map[string]interface{}{
+		"status":          OutboxPending,
+		"attempts":        0,
+		"next_attempt_at": now,
+		"updated_at":      now,
+	})
+	return result.RowsAffected, result.Error
+}
+
+// PurgeOutboxItems deletes outbox items with a status that were last updated
+// before a time. Purging failed items gives up on their chapters, which are
+// then marked notified so they are not queued again.
+func (db *DB) PurgeOutboxItems(status string, before time.Time) (int64, error) {
+	var purged int64
+	err := db.conn.Transaction(func(tx *gorm.DB) error {
+		var items []OutboxItem
+		if err := tx.Where("status = ? AND updated_at < ?", status, before).Find(&items).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+
+		ids := make([]int, 0, len(items))
+		chapterIDs := make([]int, 0)
+		for _, item := range items {
+			ids = append(ids, item.ID)
+			chapterIDs = append(chapterIDs, item.GetChapterIDs()...)
+		}
+
+		if status == OutboxFailed && len(chapterIDs) > 0 {
+			now := time.Now()
+			result := tx.Model(&SeenChapter{}).
+				Where("id IN ? AND notified = ?", chapterIDs, false).
+				Updates(map[string]interface{}{"notified": true, "notified_at": now, "updated_at": now})
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		result := tx.Where("id IN ?", ids).Delete(&OutboxItem{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
@@ -1,18 +1,48 @@
 package db
 
 import (
+	"strconv"
 	"strings"
 	"time"
 )
 
 // User represents a user who receives notifications
 type User struct {
-	ID        int       `gorm:"primaryKey" json:"id"`
-	Email     string    `gorm:"uniqueIndex" json:"email"`
-	Name      string    `json:"name"`
-	Active    bool      `gorm:"default:true" json:"active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              int       `gorm:"primaryKey" json:"id"`
+	Email           string    `gorm:"uniqueIndex" json:"email"`
+	Name            string    `json:"name"`
+	Active          bool      `gorm:"default:true" json:"active"`
+	Channels        string    `json:"channels"`         // Comma-separated notification channel names
+	DeliveryMode    string    `json:"delivery_mode"`    // immediate, hourly, daily or weekly
+	DeliveryTime    string    `json:"delivery_time"`    // HH:MM in host local time, for daily and weekly digests
+	DeliveryWeekday string    `json:"delivery_weekday"` // e.g. "monday", for weekly digests
+	LastDigestAt    time.Time `json:"last_digest_at"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Delivery modes
+const (
+	DeliveryImmediate = "immediate"
+	DeliveryHourly    = "hourly"
+	DeliveryDaily     = "daily"
+	DeliveryWeekly    = "weekly"
+)
+
+// GetDeliveryMode returns the user's delivery mode, defaulting to immediate
+func (u *User) GetDeliveryMode() string {
+	if u.DeliveryMode == "" {
+		return DeliveryImmediate
+	}
+	return u.DeliveryMode
+}
+
+// DefaultChannel is the built-in email notification channel
+const DefaultChannel = "email"
+
+// GetChannels returns the notification channels this user receives updates on
+func (u *User) GetChannels() []string {
+	return splitList(u.Channels, DefaultChannel)
 }
 
 // Subscription represents a manga subscription for a user
@@ -22,24 +52,174 @@ type Subscription struct {
 	MangaID        string    `json:"manga_id"`
 	MangaTitle     string    `json:"manga_title"`
 	Languages      string    `json:"languages"` // Comma-separated language codes
+	PreferredGroups string   `json:"preferred_groups"` // Comma-separated scanlation group names or IDs
+	BlockedGroups  string    `json:"blocked_groups"`   // Comma-separated scanlation group names or IDs
+	DedupPolicy    string    `json:"dedup_policy"`     // all, first, group or wait
+	ContentRatings string    `json:"content_ratings"`  // Comma-separated content ratings; empty uses the configured default
+	DedupWindow    int       `json:"dedup_window"`     // Minutes to wait for other releases, for the wait policy
 	LastCheckTime  time.Time `json:"last_check_time"`
+	CheckTruncated bool      `json:"check_truncated"` // LastCheckTime is the last chapter read by a check that hit the chapter limit
 	LastChapterTime time.Time `json:"last_chapter_time"`
 	Active         bool      `gorm:"default:true" json:"active"`
+	Imported       bool      `json:"imported"` // Created by importing followed manga; only these are deactivated when follows are synced
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 // GetLanguages returns the list of languages for this subscription
 func (s *Subscription) GetLanguages() []string {
-	if s.Languages == "" {
-		return []string{"en"} // Default to English
+	return splitList(s.Languages, "en") // Default to English
+}
+
+// Duplicate release policies, for the same chapter released by several groups
+const (
+	DedupAll   = "all"   // Notify every release
+	DedupFirst = "first" // Notify the first release only
+	DedupGroup = "group" // Notify the first release of each group
+	DedupWait  = "wait"  // Wait DedupWindow minutes, then notify the preferred release
+)
+
+// GetDedupPolicy returns the subscription's duplicate release policy,
+// defaulting to notifying every release
+func (s *Subscription) GetDedupPolicy() string {
+	if s.DedupPolicy == "" {
+		return DedupAll
+	}
+	return s.DedupPolicy
+}
+
+// GetContentRatings returns the content ratings whose chapters are notified,
+// or the given defaults if the subscription does not set any
+func (s *Subscription) GetContentRatings(defaults []string) []string {
+	if s.ContentRatings == "" {
+		return defaults
+	}
+	return splitList(s.ContentRatings, "")
+}
+
+// GetPreferredGroups returns the scanlation groups whose releases are preferred
+func (s *Subscription) GetPreferredGroups() []string {
+	return splitList(s.PreferredGroups, "")
+}
+
+// GetBlockedGroups returns the scanlation groups whose releases are ignored
+func (s *Subscription) GetBlockedGroups() []string {
+	return splitList(s.BlockedGroups, "")
+}
+
+// splitList splits a comma-separated string and trims spaces, returning the
+// fallback value if the string is empty, or no items if there is no fallback
+func splitList(value, fallback string) []string {
+	if value == "" {
+		if fallback == "" {
+			return []string{}
+		}
+		return []string{fallback}
 	}
 	
-	// Split the comma-separated language string and trim spaces
-	languages := strings.Split(s.Languages, ",")
-	for i, lang := range languages {
-		languages[i] = strings.TrimSpace(lang)
+	items := strings.Split(value, ",")
+	for i, item := range items {
+		items[i] = strings.TrimSpace(item)
 	}
 	
-	return languages
-}
\ No newline at end of file
+	return items
+}
+
+// SeenChapter records a chapter found for a subscription and whether the
+// subscriber has been notified about it. It makes notifications exactly-once
+// across restarts and failed deliveries.
+type SeenChapter struct {
+	ID                 int        `gorm:"primaryKey" json:"id"`
+	SubscriptionID     int        `gorm:"uniqueIndex:idx_seen_chapter" json:"subscription_id"`
+	ChapterID          string     `gorm:"uniqueIndex:idx_seen_chapter" json:"chapter_id"`
+	MangaID            string     `json:"manga_id"`
+	Title              string     `json:"title"`
+	Volume             string     `json:"volume"`
+	Chapter            string     `json:"chapter"`
+	TranslatedLanguage string     `json:"translated_language"`
+	Groups             string     `json:"groups"` // Comma-separated scanlation group IDs
+	GroupNames         string     `json:"group_names"` // Newline-separated scanlation group names, aligned with Groups
+	ReleaseKey         string     `gorm:"index" json:"release_key"` // Language, volume and chapter number; identifies duplicate releases
+	PublishAt          time.Time  `json:"publish_at"`
+	ChapterCreatedAt   time.Time  `json:"chapter_created_at"`
+	QueuedChannels     string     `json:"queued_channels"`    // Comma-separated channels with an outbox item for the chapter
+	DeliveredChannels  string     `json:"delivered_channels"` // Comma-separated channels that confirmed delivery
+	Notified           bool       `gorm:"index" json:"notified"` // Delivered on every channel of the subscriber, superseded, or given up
+	Superseded         bool       `json:"superseded"`            // Skipped as a duplicate of another release
+	NotifiedAt         *time.Time `json:"notified_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// IsDelivered reports whether the chapter has been delivered on a channel
+func (c *SeenChapter) IsDelivered(channel string) bool {
+	return hasChannel(c.DeliveredChannels, channel)
+}
+
+// IsQueued reports whether the chapter has been queued for delivery on a channel
+func (c *SeenChapter) IsQueued(channel string) bool {
+	return hasChannel(c.QueuedChannels, channel)
+}
+
+// hasChannel reports whether a comma-separated channel list contains a channel
+func hasChannel(channels, channel string) bool {
+	if channels == "" {
+		return false
+	}
+	for _, c := range strings.Split(channels, ",") {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// addChannel appends a channel to a comma-separated channel list
+func addChannel(channels, channel string) string {
+	switch {
+	case hasChannel(channels, channel):
+		return channels
+	case channels == "":
+		return channel
+	default:
+		return channels + "," + channel
+	}
+}
+
+// Outbox item statuses
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxFailed  = "failed" // Gave up after the maximum number of attempts
+)
+
+// OutboxItem is a notification waiting for delivery on one channel. Items
+// reference their chapters in the ledger, which is only marked delivered once
+// the item is sent. Failed items are kept until purged.
+type OutboxItem struct {
+	ID            int        `gorm:"primaryKey" json:"id"`
+	UserID        int        `gorm:"index" json:"user_id"`
+	Channel       string     `json:"channel"`
+	Digest        bool       `json:"digest"`   // One message for several series
+	MangaID       string     `json:"manga_id"` // Empty for digests
+	Summary       string     `json:"summary"`  // Manga title, or the series of a digest
+	Chapters      string     `json:"chapters"` // Comma-separated SeenChapter IDs
+	Status        string     `gorm:"index" json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"index" json:"next_attempt_at"`
+	LastError     string     `json:"last_error"`
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// GetChapterIDs returns the ledger entry IDs of the item's chapters
+func (o *OutboxItem) GetChapterIDs() []int {
+	ids := make([]int, 0)
+	for _, item := range splitList(o.Chapters, "") {
+		if id, err := strconv.Atoi(item); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,75 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mangadex-cli/internal/api"
+
+	"github.com/go-gomail/gomail"
+)
+
+// DigestSection is one series in a digest email
+type DigestSection struct {
+	Manga    *api.Manga
+	Chapters []api.Chapter
+}
+
+// SendDigest sends a single email covering new chapters across several series
+func (e *EmailService) SendDigest(recipient Recipient, sections []DigestSection) error {
+	return e.SendDigestContext(context.Background(), recipient, sections)
+}
+
+// SendDigestContext is like SendDigest but can be cancelled through the context
+func (e *EmailService) SendDigestContext(ctx context.Context, recipient Recipient, sections []DigestSection) error {
+	total := 0
+	for _, section := range sections {
+		total += len(section.Chapters)
+	}
+
+	// Create message
+	m := gomail.NewMessage()
+	m.SetHeader("From", e.createFromHeader())
+	m.SetHeader("To", recipient.Email)
+	m.SetHeader("Subject", fmt.Sprintf("MangaDex Digest: %d new chapter(s) in %d series", total, len(sections)))
+
+	// Generate HTML and text content
+	data := DigestData{
+		User:          recipient,
+		TotalChapters: total,
+		SentAt:        time.Now(),
+	}
+
+	// A digest covers every series, so unsubscribing stops all notifications
+	if e.Unsubscribe != nil && recipient.ID != 0 {
+		data.UnsubscribeURL = e.Unsubscribe.UserURL(recipient.ID)
+		setUnsubscribeHeaders(m, data.UnsubscribeURL)
+	}
+	for _, section := range sections {
+		templateManga := newTemplateManga(section.Manga, e.DescriptionLength)
+		e.embedCover(ctx, m, section.Manga, &templateManga)
+		chapters, volumes, groups := newTemplateChapters(section.Chapters)
+		data.Series = append(data.Series, DigestSeries{
+			Manga:    templateManga,
+			Chapters: chapters,
+			Volumes:  volumes,
+			Groups:   groups,
+		})
+	}
+
+	html, text, err := e.templates.Render(TemplateDigest, data)
+	if err != nil {
+		return err
+	}
+
+	m.SetBody("text/html", html)
+	m.AddAlternative("text/plain", text)
+
+	// Send the email
+	if err := e.send(ctx, m); err != nil {
+		return fmt.Errorf("failed to send digest: %w", err)
+	}
+
+	return nil
+}
@@ -1,51 +1,98 @@
 package email
 
 import (
+	"context"
 	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"log"
 	"mangadex-cli/internal/api"
 	"mangadex-cli/internal/config"
-	"strings"
+	"mangadex-cli/internal/unsubscribe"
 	"time"
 	
 	"github.com/go-gomail/gomail"
 )
 
-// EmailService handles sending email notifications
+// EmailService handles sending email notifications. Messages are delivered
+// by a transport, SMTP unless another one is set.
 type EmailService struct {
-	Config config.SMTPConfig
+	Config            config.SMTPConfig
+	DescriptionLength int // Maximum description length, zero for no limit
+	templates         *Templates
+	Unsubscribe       *unsubscribe.Signer // Adds unsubscribe links and headers; nil to leave them out
+	Covers            *api.CoverCache     // Embeds cover thumbnails inline; nil to link to the remote image
+	limiter           *api.RateLimiter
+	transport         Transport
 }
 
-// NewEmailService creates a new email service
+// NewEmailService creates a new email service sending over SMTP, using the
+// default templates
 func NewEmailService(config config.SMTPConfig) *EmailService {
+	templates, err := LoadTemplates("")
+	if err != nil {
+		// The defaults are embedded, so this is a programming error
+		panic(err)
+	}
+	
 	return &EmailService{
-		Config: config,
+		Config:    config,
+		templates: templates,
+		limiter:   api.NewRateLimiter(config.SendRate, 1),
+		transport: newSMTPTransport(config),
 	}
 }
 
-// Connect tests the connection to the email server
-func (e *EmailService) Connect() error {
-	dialer := e.createDialer()
-	sender, err := dialer.Dial()
+// SetTransport replaces the transport messages are delivered by
+func (e *EmailService) SetTransport(transport Transport) {
+	e.transport = transport
+}
+
+// OnRefreshTokenRotated sets a function called with the new refresh token
+// when the OAuth provider replaces it, so it can be saved
+func (e *EmailService) OnRefreshTokenRotated(fn func(refreshToken string)) {
+	if t, ok := e.transport.(*smtpTransport); ok && t.tokens != nil {
+		t.tokens.OnRefreshToken = fn
+	}
+}
+
+// LoadTemplates replaces the default templates with any overrides found in dir
+func (e *EmailService) LoadTemplates(dir string) error {
+	templates, err := LoadTemplates(dir)
 	if err != nil {
-		return fmt.Errorf("failed to connect to email server: %w", err)
+		return err
 	}
-	defer sender.Close()
 	
+	e.templates = templates
 	return nil
 }
 
-// Disconnect is a no-op as we don't maintain persistent connections
+// Connect prepares the transport, such as opening an SMTP session that is
+// reused by every message until Disconnect is called
+func (e *EmailService) Connect() error {
+	return e.transport.Connect(context.Background())
+}
+
+// Disconnect releases anything the transport holds between messages
 func (e *EmailService) Disconnect() error {
-	// No persistent connection to close
-	return nil
+	return e.transport.Close()
 }
 
-// createDialer creates a new gomail dialer with the configured settings
-func (e *EmailService) createDialer() *gomail.Dialer {
-	dialer := gomail.NewDialer(e.Config.Server, e.Config.Port, e.Config.Username, e.Config.Password)
-	dialer.SSL = e.Config.UseTLS
+// send delivers a message through the transport at the configured send rate
+func (e *EmailService) send(ctx context.Context, m *gomail.Message) error {
+	if err := e.limiter.WaitContext(ctx); err != nil {
+		return err
+	}
 	
-	return dialer
+	return gomail.Send(gomail.SendFunc(func(from string, to []string, msg io.WriterTo) error {
+		return e.transport.Send(ctx, from, to, msg)
+	}), m)
+}
+
+// ConnectionInfo describes where messages go, e.g. the security mode, TLS
+// version and auth mechanism negotiated by the last SMTP connection
+func (e *EmailService) ConnectionInfo() string {
+	return e.transport.Describe()
 }
 
 // SendTestEmail sends a test email to verify configuration
@@ -57,49 +104,19 @@ func (e *EmailService) SendTestEmail(recipient string) error {
 	m.SetHeader("Subject", "MangaDex CLI Notification - Test Email")
 	
 	// Email body
-	body := `
-	<html>
-		<head>
-			<style>
-				body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
-				.container { max-width: 600px; margin: 0 auto; padding: 20px; }
-				.header { background-color: #4a86e8; color: white; padding: 10px; text-align: center; }
-				.footer { font-size: 12px; color: #777; margin-top: 30px; text-align: center; }
-			</style>
-		</head>
-		<body>
-			<div class="container">
-				<div class="header">
-					<h1>MangaDex CLI Notification</h1>
-				</div>
-				<div class="content">
-					<h2>Test Email</h2>
-					<p>This is a test email sent from your MangaDex CLI Notification Service.</p>
-					<p>If you're receiving this message, your email configuration is working correctly!</p>
-					<p>Time: %s</p>
-				</div>
-				<div class="footer">
-					<p>This email was sent from the MangaDex CLI Notification Service.</p>
-				</div>
-			</div>
-		</body>
-	</html>
-	`
-	
-	formattedBody := fmt.Sprintf(body, time.Now().Format(time.RFC1123))
+	html, text, err := e.templates.Render(TemplateTest, TestData{
+		User:   Recipient{Email: recipient},
+		SentAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
 	
-	m.SetBody("text/html", formattedBody)
-	m.AddAlternative("text/plain", fmt.Sprintf(
-		"MangaDex CLI Notification - Test Email\n\n"+
-			"This is a test email sent from your MangaDex CLI Notification Service.\n"+
-			"If you're receiving this message, your email configuration is working correctly!\n\n"+
-			"Time: %s\n\n"+
-			"This email was sent from the MangaDex CLI Notification Service.",
-		time.Now().Format(time.RFC1123)))
+	m.SetBody("text/html", html)
+	m.AddAlternative("text/plain", text)
 	
 	// Send the email
-	dialer := e.createDialer()
-	if err := dialer.DialAndSend(m); err != nil {
+	if err := e.send(context.Background(), m); err != nil {
 		return fmt.Errorf("failed to send test email: %w", err)
 	}
 	
@@ -107,36 +124,73 @@ func (e *EmailService) SendTestEmail(recipient string) error {
 }
 
 // SendNotification sends a manga update notification
-func (e *EmailService) SendNotification(recipient string, manga *api.Manga, chapters []api.Chapter) error {
+func (e *EmailService) SendNotification(recipient Recipient, manga *api.Manga, chapters []api.Chapter) error {
+	return e.SendNotificationContext(context.Background(), recipient, manga, chapters)
+}
+
+// SendNotificationContext is like SendNotification but can be cancelled
+// through the context
+func (e *EmailService) SendNotificationContext(ctx context.Context, recipient Recipient, manga *api.Manga, chapters []api.Chapter) error {
 	// Create message
 	m := gomail.NewMessage()
 	m.SetHeader("From", e.createFromHeader())
-	m.SetHeader("To", recipient)
+	m.SetHeader("To", recipient.Email)
 	
 	// Subject line
 	var subject string
 	if len(chapters) == 1 {
-		subject = fmt.Sprintf("New Chapter: %s - Chapter %s", manga.GetTitle(), chapters[0].Chapter)
+		subject = fmt.Sprintf("New Chapter: %s - %s", manga.GetTitle(), chapters[0].Number().Label())
 	} else {
 		subject = fmt.Sprintf("%d New Chapters for %s", len(chapters), manga.GetTitle())
 	}
 	m.SetHeader("Subject", subject)
 	
+	// One-click unsubscribe stops this manga only
+	unsubscribeURL, unsubscribeAllURL := "", ""
+	if e.Unsubscribe != nil && recipient.ID != 0 {
+		unsubscribeURL = e.Unsubscribe.MangaURL(recipient.ID, manga.ID)
+		unsubscribeAllURL = e.Unsubscribe.UserURL(recipient.ID)
+		setUnsubscribeHeaders(m, unsubscribeURL)
+	}
+	
 	// Generate HTML and text content
-	html, text := e.renderNotificationTemplate(manga, chapters)
+	templateManga := newTemplateManga(manga, e.DescriptionLength)
+	e.embedCover(ctx, m, manga, &templateManga)
+	templateChapters, volumes, groups := newTemplateChapters(chapters)
+	html, text, err := e.templates.Render(TemplateNotification, NotificationData{
+		User:              recipient,
+		Manga:             templateManga,
+		Chapters:          templateChapters,
+		Volumes:           volumes,
+		Groups:            groups,
+		UnsubscribeURL:    unsubscribeURL,
+		UnsubscribeAllURL: unsubscribeAllURL,
+		SentAt:            time.Now(),
+	})
+	if err != nil {
+		return err
+	}
 	
 	m.SetBody("text/html", html)
 	m.AddAlternative("text/plain", text)
 	
 	// Send the email
-	dialer := e.createDialer()
-	if err := dialer.DialAndSend(m); err != nil {
+	if err := e.send(ctx, m); err != nil {
 		return fmt.Errorf("failed to send notification: %w", err)
 	}
 	
 	return nil
 }
 
+// RenderSample renders a template kind with example data, for previewing templates
+func (e *EmailService) RenderSample(kind string) (string, string, error) {
+	data, err := SampleData(kind)
+	if err != nil {
+		return "", "", err
+	}
+	return e.templates.Render(kind, data)
+}
+
 // createFromHeader creates the From header with proper formatting
 func (e *EmailService) createFromHeader() string {
 	if e.Config.FromName != "" {
@@ -145,155 +199,29 @@ func (e *EmailService) createFromHeader() string {
 	return e.Config.FromEmail
 }
 
-// renderNotificationTemplate generates the email content for a notification
-func (e *EmailService) renderNotificationTemplate(manga *api.Manga, chapters []api.Chapter) (string, string) {
-	// Sort chapters in ascending order
-	// This is a simple implementation; for production, we would want to sort numerically
-	// but chapter numbers can be complex ("10.5", "Extra", etc.)
-	
-	// HTML Template
-	htmlTemplate := `
-	<html>
-		<head>
-			<style>
-				body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
-				.container { max-width: 600px; margin: 0 auto; padding: 20px; }
-				.header { background-color: #4a86e8; color: white; padding: 10px; text-align: center; }
-				.manga-info { display: flex; margin: 20px 0; }
-				.manga-cover { width: 120px; height: auto; margin-right: 20px; }
-				.manga-details { flex: 1; }
-				.chapter-list { margin: 20px 0; }
-				.chapter { padding: 10px; border-bottom: 1px solid #eee; }
-				.chapter:last-child { border-bottom: none; }
-				.chapter-number { font-weight: bold; }
-				.footer { font-size: 12px; color: #777; margin-top: 30px; text-align: center; }
-				.read-button { display: inline-block; background-color: #4a86e8; color: white; padding: 8px 15px; text-decoration: none; border-radius: 3px; }
-			</style>
-		</head>
-		<body>
-			<div class="container">
-				<div class="header">
-					<h1>MangaDex Update</h1>
-				</div>
-				<div class="content">
-					<h2>New Chapters for %s</h2>
-					
-					<div class="manga-info">
-						%s
-						<div class="manga-details">
-							<p><strong>Status:</strong> %s</p>
-							<p>%s</p>
-						</div>
-					</div>
-					
-					<div class="chapter-list">
-						<h3>New Chapters:</h3>
-						%s
-					</div>
-					
-					<p><a href="https://mangadex.org/title/%s" class="read-button">View on MangaDex</a></p>
-				</div>
-				<div class="footer">
-					<p>This email was sent from the MangaDex CLI Notification Service.</p>
-					<p>Time: %s</p>
-				</div>
-			</div>
-		</body>
-	</html>
-	`
-	
-	// Generate cover image HTML if available
-	var coverHTML string
-	if manga.CoverArtURL != "" {
-		coverHTML = fmt.Sprintf(`<img src="%s" class="manga-cover" alt="%s Cover">`, manga.CoverArtURL, manga.GetTitle())
+// embedCover attaches the manga's cached cover thumbnail as an inline image
+// and points the template at it, as most email clients block remote images.
+// If the cover cannot be fetched, the template keeps the remote URL.
+func (e *EmailService) embedCover(ctx context.Context, m *gomail.Message, manga *api.Manga, templateManga *TemplateManga) {
+	if e.Covers == nil || manga.CoverArtURL == "" {
+		return
 	}
 	
-	// Generate chapter list HTML
-	var chapterListHTML strings.Builder
-	for _, chapter := range chapters {
-		chapterHTML := `<div class="chapter">
-			<span class="chapter-number">Chapter %s</span>
-			%s
-			<p>Language: %s</p>
-			<p>Published: %s</p>
-			<p><a href="https://mangadex.org/chapter/%s">Read Chapter</a></p>
-		</div>`
-		
-		title := ""
-		if chapter.Title != "" {
-			title = fmt.Sprintf(`<p>%s</p>`, chapter.Title)
-		}
-		
-		chapterListHTML.WriteString(fmt.Sprintf(
-			chapterHTML,
-			chapter.Chapter,
-			title,
-			chapter.TranslatedLanguage,
-			chapter.PublishAt.Format("January 2, 2006"),
-			chapter.ID,
-		))
+	path, err := e.Covers.GetContext(ctx, manga)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
 	}
 	
-	// Format the HTML template
-	html := fmt.Sprintf(
-		htmlTemplate,
-		manga.GetTitle(),
-		coverHTML,
-		manga.Status,
-		manga.GetDescription(),
-		chapterListHTML.String(),
-		manga.ID,
-		time.Now().Format(time.RFC1123),
-	)
-	
-	// Text version
-	textTemplate := `
-MangaDex Update - %s
-
-New Chapters for %s
-
-Status: %s
-
-%s
-
-New Chapters:
-%s
-
-View on MangaDex: https://mangadex.org/title/%s
+	// The file name doubles as the Content-ID
+	name := "cover-" + manga.ID + ".jpg"
+	m.Embed(path, gomail.Rename(name))
+	templateManga.CoverURL = htmltemplate.URL("cid:" + name)
+}
 
-This email was sent from the MangaDex CLI Notification Service.
-Time: %s
-`
-	
-	// Generate chapter list text
-	var chapterListText strings.Builder
-	for _, chapter := range chapters {
-		titleText := ""
-		if chapter.Title != "" {
-			titleText = fmt.Sprintf(" - %s", chapter.Title)
-		}
-		
-		chapterListText.WriteString(fmt.Sprintf(
-			"- Chapter %s%s | Language: %s | Published: %s | https://mangadex.org/chapter/%s\n",
-			chapter.Chapter,
-			titleText,
-			chapter.TranslatedLanguage,
-			chapter.PublishAt.Format("January 2, 2006"),
-			chapter.ID,
-		))
-	}
-	
-	// Format the text template
-	text := fmt.Sprintf(
-		textTemplate,
-		manga.GetTitle(),
-		manga.GetTitle(),
-		manga.Status,
-		manga.GetDescription(),
-		chapterListText.String(),
-		manga.ID,
-		time.Now().Format(time.RFC1123),
-	)
-	
-	return html, text
-}
\ No newline at end of file
+// setUnsubscribeHeaders adds the List-Unsubscribe header and marks it as
+// supporting one-click unsubscribe (RFC 8058)
+func setUnsubscribeHeaders(m *gomail.Message, link string) {
+	m.SetHeader("List-Unsubscribe", "<"+link+">")
+	m.SetHeader("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+}
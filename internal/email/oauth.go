@@ -0,0 +1,476 @@
+package email
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"mangadex-cli/internal/config"
+)
+
+// OAuth2 providers with built-in endpoints
+const (
+	ProviderGoogle    = "google"
+	ProviderMicrosoft = "microsoft"
+)
+
+// tokenExpiryMargin renews access tokens this long before they expire
+const tokenExpiryMargin = time.Minute
+
+// defaultTokenLifetime is assumed for access tokens sent without expires_in
+const defaultTokenLifetime = time.Hour
+
+// oauthHTTPClient is used for every token request
+var oauthHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// OAuthEndpoints are the URLs and scope used to get XOAUTH2 access tokens.
+// Sign-in uses the device flow if there is a device authorization URL, and
+// the loopback authorization code flow otherwise.
+type OAuthEndpoints struct {
+	AuthURL       string
+	DeviceAuthURL string
+	TokenURL      string
+	Scope         string
+}
+
+// ResolveOAuthEndpoints fills in endpoints left empty in the settings from
+// the provider's defaults
+func ResolveOAuthEndpoints(settings config.SMTPOAuthConfig) (OAuthEndpoints, error) {
+	endpoints := OAuthEndpoints{
+		AuthURL:       settings.AuthURL,
+		DeviceAuthURL: settings.DeviceAuthURL,
+		TokenURL:      settings.TokenURL,
+		Scope:         settings.Scope,
+	}
+
+	var defaults OAuthEndpoints
+	switch strings.ToLower(settings.Provider) {
+	case ProviderGoogle:
+		// Google's device flow does not allow Gmail scopes
+		defaults = OAuthEndpoints{
+			AuthURL:  "https://accounts.google.com/o/oauth2/v2/auth",
+			TokenURL: "https://oauth2.googleapis.com/token",
+			Scope:    "https://mail.google.com/",
+		}
+	case ProviderMicrosoft:
+		tenant := settings.TenantID
+		if tenant == "" {
+			tenant = "organizations"
+		}
+		base := "https://login.microsoftonline.com/" + url.PathEscape(tenant) + "/oauth2/v2.0"
+		defaults = OAuthEndpoints{
+			DeviceAuthURL: base + "/devicecode",
+			TokenURL:      base + "/token",
+			Scope:         "https://outlook.office.com/SMTP.Send offline_access",
+		}
+		// App-only tokens are requested for the resource's default scope
+		if settings.RefreshToken == "" && settings.ClientSecret != "" {
+			defaults.Scope = "https://outlook.office365.com/.default"
+		}
+	case "":
+	default:
+		return endpoints, fmt.Errorf("unknown OAuth provider %q, must be google or microsoft", settings.Provider)
+	}
+
+	if endpoints.AuthURL == "" {
+		endpoints.AuthURL = defaults.AuthURL
+	}
+	if endpoints.DeviceAuthURL == "" {
+		endpoints.DeviceAuthURL = defaults.DeviceAuthURL
+	}
+	if endpoints.TokenURL == "" {
+		endpoints.TokenURL = defaults.TokenURL
+	}
+	if endpoints.Scope == "" {
+		endpoints.Scope = defaults.Scope
+	}
+
+	if endpoints.TokenURL == "" {
+		return endpoints, fmt.Errorf("OAuth token URL or provider must be set")
+	}
+	return endpoints, nil
+}
+
+// tokenResponse is a token endpoint reply, including OAuth error replies
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// postForm posts a form to an OAuth endpoint and decodes the JSON reply.
+// OAuth errors are returned in the reply rather than as an error.
+func postForm(ctx context.Context, endpoint string, form url.Values, reply interface{}) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create OAuth request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := oauthHTTPClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("OAuth request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to read OAuth response: %w", err)
+	}
+	if err := json.Unmarshal(body, reply); err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to parse OAuth response with status code %d: %s", resp.StatusCode, string(body))
+	}
+	return resp.StatusCode, nil
+}
+
+// oauthError describes an OAuth error reply
+func oauthError(reply tokenResponse, status int) error {
+	if reply.ErrorDescription != "" {
+		return fmt.Errorf("%s: %s", reply.Error, reply.ErrorDescription)
+	}
+	if reply.Error != "" {
+		return fmt.Errorf("%s", reply.Error)
+	}
+	return fmt.Errorf("token request failed with status code %d", status)
+}
+
+// TokenSource gets XOAUTH2 access tokens with a refresh token, or with the
+// client credentials grant if there is none, and caches them until shortly
+// before they expire
+type TokenSource struct {
+	settings config.SMTPOAuthConfig
+
+	// OnRefreshToken is called when the provider issues a new refresh token,
+	// so it can be stored for the next run
+	OnRefreshToken func(refreshToken string)
+
+	mu          sync.Mutex
+	accessToken string
+	expiry      time.Time
+}
+
+// NewTokenSource creates a token source for the OAuth settings
+func NewTokenSource(settings config.SMTPOAuthConfig) *TokenSource {
+	return &TokenSource{settings: settings}
+}
+
+// Token returns a valid access token, requesting a new one if needed
+func (s *TokenSource) Token() (string, error) {
+	return s.TokenContext(context.Background())
+}
+
+// TokenContext is like Token but the request can be cancelled through the context
+func (s *TokenSource) TokenContext(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.accessToken != "" && time.Now().Before(s.expiry) {
+		return s.accessToken, nil
+	}
+
+	endpoints, err := ResolveOAuthEndpoints(s.settings)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("client_id", s.settings.ClientID)
+	if s.settings.ClientSecret != "" {
+		form.Set("client_secret", s.settings.ClientSecret)
+	}
+	switch {
+	case s.settings.RefreshToken != "":
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", s.settings.RefreshToken)
+	case s.settings.ClientSecret != "":
+		form.Set("grant_type", "client_credentials")
+		form.Set("scope", endpoints.Scope)
+	default:
+		return "", fmt.Errorf("no OAuth refresh token or client secret; run 'config smtp-oauth' first")
+	}
+
+	var reply tokenResponse
+	status, err := postForm(ctx, endpoints.TokenURL, form, &reply)
+	if err != nil {
+		return "", err
+	}
+	if reply.AccessToken == "" {
+		return "", fmt.Errorf("failed to get OAuth access token: %w", oauthError(reply, status))
+	}
+
+	s.accessToken = reply.AccessToken
+	s.expiry = time.Now().Add(tokenLifetime(reply.ExpiresIn))
+
+	// Some providers rotate refresh tokens on every use
+	if reply.RefreshToken != "" && reply.RefreshToken != s.settings.RefreshToken {
+		s.settings.RefreshToken = reply.RefreshToken
+		if s.OnRefreshToken != nil {
+			s.OnRefreshToken(reply.RefreshToken)
+		}
+	}
+
+	return s.accessToken, nil
+}
+
+// tokenLifetime returns how long to use an access token that expires in
+// expiresIn seconds. Tokens are renewed tokenExpiryMargin early, unless they
+// are too short-lived for the margin.
+func tokenLifetime(expiresIn int) time.Duration {
+	lifetime := time.Duration(expiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = defaultTokenLifetime
+	}
+	if lifetime > tokenExpiryMargin {
+		lifetime -= tokenExpiryMargin
+	}
+	return lifetime
+}
+
+// Invalidate drops the cached access token, e.g. after the server rejected it
+func (s *TokenSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.accessToken = ""
+}
+
+// DeviceCode is a pending device authorization the user completes in a browser
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	VerificationURL string `json:"verification_url"` // Google's name for VerificationURI
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+	Message         string `json:"message"`
+}
+
+// URL returns the page where the user enters the code
+func (d *DeviceCode) URL() string {
+	if d.VerificationURI != "" {
+		return d.VerificationURI
+	}
+	return d.VerificationURL
+}
+
+// StartDeviceFlow begins the OAuth device authorization flow
+func StartDeviceFlow(ctx context.Context, settings config.SMTPOAuthConfig) (*DeviceCode, error) {
+	endpoints, err := ResolveOAuthEndpoints(settings)
+	if err != nil {
+		return nil, err
+	}
+	if endpoints.DeviceAuthURL == "" {
+		return nil, fmt.Errorf("OAuth device authorization URL or provider must be set")
+	}
+
+	form := url.Values{}
+	form.Set("client_id", settings.ClientID)
+	form.Set("scope", endpoints.Scope)
+
+	var reply struct {
+		DeviceCode
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := postForm(ctx, endpoints.DeviceAuthURL, form, &reply)
+	if err != nil {
+		return nil, err
+	}
+	if reply.DeviceCode.DeviceCode == "" {
+		return nil, fmt.Errorf("failed to start device authorization: %w",
+			oauthError(tokenResponse{Error: reply.Error, ErrorDescription: reply.ErrorDescription}, status))
+	}
+
+	return &reply.DeviceCode, nil
+}
+
+// PollDeviceFlow waits for the user to complete a device authorization and
+// returns the refresh token. It stops waiting when the context is done.
+func PollDeviceFlow(ctx context.Context, settings config.SMTPOAuthConfig, code *DeviceCode) (string, error) {
+	endpoints, err := ResolveOAuthEndpoints(settings)
+	if err != nil {
+		return "", err
+	}
+
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+
+	form := url.Values{}
+	form.Set("client_id", settings.ClientID)
+	if settings.ClientSecret != "" {
+		form.Set("client_secret", settings.ClientSecret)
+	}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:device_code")
+	form.Set("device_code", code.DeviceCode)
+
+	for time.Now().Before(deadline) {
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", ctx.Err()
+		case <-timer.C:
+		}
+
+		var reply tokenResponse
+		status, err := postForm(ctx, endpoints.TokenURL, form, &reply)
+		if err != nil {
+			return "", err
+		}
+
+		switch reply.Error {
+		case "":
+			if reply.RefreshToken == "" {
+				return "", fmt.Errorf("provider did not return a refresh token; check the offline access scope")
+			}
+			return reply.RefreshToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		default:
+			return "", fmt.Errorf("device authorization failed: %w", oauthError(reply, status))
+		}
+	}
+
+	return "", fmt.Errorf("device authorization expired")
+}
+
+// AuthorizeLoopback runs the authorization code flow for installed apps with
+// PKCE: the user signs in with a browser on this machine, which is redirected
+// back to a temporary server on the loopback interface with the code. show is
+// called with the link to open. It returns the refresh token.
+func AuthorizeLoopback(ctx context.Context, settings config.SMTPOAuthConfig, show func(authURL string)) (string, error) {
+	endpoints, err := ResolveOAuthEndpoints(settings)
+	if err != nil {
+		return "", err
+	}
+	if endpoints.AuthURL == "" {
+		return "", fmt.Errorf("OAuth authorization URL or provider must be set")
+	}
+
+	state, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to listen for the OAuth redirect: %w", err)
+	}
+	defer listener.Close()
+	redirectURI := "http://" + listener.Addr().String() + "/"
+
+	// Only the redirect carrying our state is answered with a result
+	type callback struct {
+		code string
+		err  error
+	}
+	callbacks := make(chan callback, 1)
+	server := &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			if query.Get("state") != state {
+				http.Error(w, "Unknown authorization request", http.StatusBadRequest)
+				return
+			}
+
+			var result callback
+			switch {
+			case query.Get("error") != "":
+				result.err = fmt.Errorf("authorization failed: %s", query.Get("error"))
+			case query.Get("code") == "":
+				result.err = fmt.Errorf("authorization failed: no code in the redirect")
+			default:
+				result.code = query.Get("code")
+			}
+
+			if result.err != nil {
+				fmt.Fprintln(w, "Authorization failed. You can close this window.")
+			} else {
+				fmt.Fprintln(w, "Authorization complete. You can close this window.")
+			}
+			select {
+			case callbacks <- result:
+			default:
+			}
+		}),
+	}
+	go server.Serve(listener)
+	defer server.Close()
+
+	query := url.Values{}
+	query.Set("client_id", settings.ClientID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("response_type", "code")
+	query.Set("scope", endpoints.Scope)
+	query.Set("state", state)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	// Ask for a refresh token, even if the user authorized the client before
+	query.Set("access_type", "offline")
+	query.Set("prompt", "consent")
+	show(endpoints.AuthURL + "?" + query.Encode())
+
+	var result callback
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case result = <-callbacks:
+	}
+	if result.err != nil {
+		return "", result.err
+	}
+
+	form := url.Values{}
+	form.Set("client_id", settings.ClientID)
+	if settings.ClientSecret != "" {
+		form.Set("client_secret", settings.ClientSecret)
+	}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", result.code)
+	form.Set("redirect_uri", redirectURI)
+	form.Set("code_verifier", verifier)
+
+	var reply tokenResponse
+	status, err := postForm(ctx, endpoints.TokenURL, form, &reply)
+	if err != nil {
+		return "", err
+	}
+	if reply.Error != "" {
+		return "", fmt.Errorf("authorization failed: %w", oauthError(reply, status))
+	}
+	if reply.RefreshToken == "" {
+		return "", fmt.Errorf("provider did not return a refresh token; check the offline access scope")
+	}
+	return reply.RefreshToken, nil
+}
+
+// randomString returns a random URL-safe string for OAuth state and PKCE
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,226 @@
+package email
+
+import (
+	"html"
+	htmltemplate "html/template"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MangaDex descriptions are user-submitted Markdown, often mixed with BBCode
+// and HTML entities. They are converted to a small whitelist of HTML tags for
+// the HTML body and to clean plain text for the text alternative. All other
+// markup is escaped or dropped.
+
+// DefaultDescriptionLength is the default maximum length of a description
+const DefaultDescriptionLength = 500
+
+var (
+	mdLinkPattern   = regexp.MustCompile(`\[([^\[\]]+)\]\(([^()\s]+)\)`)
+	bbURLPattern    = regexp.MustCompile(`(?i)\[url=([^\]\s]+)\](.*?)\[/url\]`)
+	bbTagPattern    = regexp.MustCompile(`(?i)\[/?(b|i|u|s|spoiler|url|img|quote|code|center|right|left|size(=[^\]]*)?|color(=[^\]]*)?)\]`)
+	htmlTagPattern  = regexp.MustCompile(`</?[a-zA-Z][^<>]*>`)
+	boldPattern     = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	italicPattern   = regexp.MustCompile(`\*([^*\s][^*]*?)\*|\b_([^_\s][^_]*?)_\b`)
+	strikePattern   = regexp.MustCompile(`~~(.+?)~~`)
+	bbBoldPattern   = regexp.MustCompile(`(?i)\[b\](.*?)\[/b\]`)
+	bbItalicPattern = regexp.MustCompile(`(?i)\[i\](.*?)\[/i\]`)
+	bbUnderPattern  = regexp.MustCompile(`(?i)\[u\](.*?)\[/u\]`)
+	bbStrikePattern = regexp.MustCompile(`(?i)\[s\](.*?)\[/s\]`)
+	ruleLinePattern = regexp.MustCompile(`^\s*([-*_]\s*){3,}$`)
+	listItemPattern = regexp.MustCompile(`^\s*([-*+]|\d+\.)\s+`)
+	headingPattern  = regexp.MustCompile(`^\s*#{1,6}\s+`)
+	leftoverPattern = regexp.MustCompile(`\*\*|__|~~`)
+)
+
+// RenderDescription converts a MangaDex description to safe HTML and plain
+// text. If maxLength is positive, the description is cut at a word boundary
+// to at most that many characters.
+func RenderDescription(source string, maxLength int) (htmltemplate.HTML, string) {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = html.UnescapeString(htmlTagPattern.ReplaceAllString(source, ""))
+	source = truncateWords(strings.TrimSpace(source), maxLength)
+
+	return descriptionHTML(source), descriptionText(source)
+}
+
+// descriptionHTML renders paragraphs, list items and inline emphasis and links.
+// The source is escaped before any tags are added, so only tags produced
+// here can reach the output.
+func descriptionHTML(source string) htmltemplate.HTML {
+	var out strings.Builder
+	inList := false
+
+	closeList := func() {
+		if inList {
+			out.WriteString("</ul>")
+			inList = false
+		}
+	}
+
+	for _, paragraph := range splitParagraphs(source) {
+		for _, line := range paragraph {
+			switch {
+			case ruleLinePattern.MatchString(line):
+				closeList()
+				out.WriteString("<hr>")
+			case listItemPattern.MatchString(line):
+				if !inList {
+					out.WriteString("<ul>")
+					inList = true
+				}
+				out.WriteString("<li>" + inlineHTML(listItemPattern.ReplaceAllString(line, "")) + "</li>")
+			default:
+				closeList()
+				out.WriteString("<p>" + inlineHTML(headingPattern.ReplaceAllString(line, "")) + "</p>")
+			}
+		}
+		closeList()
+	}
+
+	return htmltemplate.HTML(out.String())
+}
+
+// inlineHTML escapes a line and converts inline Markdown and BBCode
+func inlineHTML(line string) string {
+	// Links are extracted before escaping so URLs can be validated
+	type link struct{ text, url string }
+	links := make([]link, 0)
+	placeholder := func(text, url string) string {
+		if !isSafeURL(url) {
+			return text
+		}
+		links = append(links, link{text, url})
+		return "\x00" + strconv.Itoa(len(links)-1) + "\x00"
+	}
+
+	line = mdLinkPattern.ReplaceAllStringFunc(line, func(m string) string {
+		parts := mdLinkPattern.FindStringSubmatch(m)
+		return placeholder(parts[1], parts[2])
+	})
+	line = bbURLPattern.ReplaceAllStringFunc(line, func(m string) string {
+		parts := bbURLPattern.FindStringSubmatch(m)
+		return placeholder(parts[2], parts[1])
+	})
+
+	line = html.EscapeString(line)
+	line = boldPattern.ReplaceAllString(line, "<strong>$1$2</strong>")
+	line = bbBoldPattern.ReplaceAllString(line, "<strong>$1</strong>")
+	line = italicPattern.ReplaceAllString(line, "<em>$1$2</em>")
+	line = bbItalicPattern.ReplaceAllString(line, "<em>$1</em>")
+	line = bbUnderPattern.ReplaceAllString(line, "<u>$1</u>")
+	line = strikePattern.ReplaceAllString(line, "<s>$1</s>")
+	line = bbStrikePattern.ReplaceAllString(line, "<s>$1</s>")
+	line = bbTagPattern.ReplaceAllString(line, "")
+	line = leftoverPattern.ReplaceAllString(line, "")
+
+	for i, l := range links {
+		anchor := `<a href="` + html.EscapeString(l.url) + `">` + html.EscapeString(stripInline(l.text)) + `</a>`
+		line = strings.Replace(line, "\x00"+strconv.Itoa(i)+"\x00", anchor, 1)
+	}
+
+	return line
+}
+
+// descriptionText renders the description as plain text, with links as
+// "text (url)" and all other markup removed
+func descriptionText(source string) string {
+	paragraphs := make([]string, 0)
+	for _, paragraph := range splitParagraphs(source) {
+		lines := make([]string, 0, len(paragraph))
+		for _, line := range paragraph {
+			switch {
+			case ruleLinePattern.MatchString(line):
+				lines = append(lines, "---")
+			case listItemPattern.MatchString(line):
+				lines = append(lines, "- "+textLine(listItemPattern.ReplaceAllString(line, "")))
+			default:
+				lines = append(lines, textLine(headingPattern.ReplaceAllString(line, "")))
+			}
+		}
+		paragraphs = append(paragraphs, strings.Join(lines, "\n"))
+	}
+
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// textLine converts inline markup in a line to plain text
+func textLine(line string) string {
+	line = mdLinkPattern.ReplaceAllStringFunc(line, func(m string) string {
+		parts := mdLinkPattern.FindStringSubmatch(m)
+		return linkText(parts[1], parts[2])
+	})
+	line = bbURLPattern.ReplaceAllStringFunc(line, func(m string) string {
+		parts := bbURLPattern.FindStringSubmatch(m)
+		return linkText(parts[2], parts[1])
+	})
+	return stripInline(line)
+}
+
+// linkText renders a link as plain text
+func linkText(text, url string) string {
+	text = stripInline(text)
+	if !isSafeURL(url) || text == url {
+		return text
+	}
+	return text + " (" + url + ")"
+}
+
+// stripInline removes inline emphasis and BBCode tags
+func stripInline(text string) string {
+	text = boldPattern.ReplaceAllString(text, "$1$2")
+	text = italicPattern.ReplaceAllString(text, "$1$2")
+	text = strikePattern.ReplaceAllString(text, "$1")
+	text = bbTagPattern.ReplaceAllString(text, "")
+	return leftoverPattern.ReplaceAllString(text, "")
+}
+
+// splitParagraphs splits text on blank lines into paragraphs of non-empty lines
+func splitParagraphs(text string) [][]string {
+	paragraphs := make([][]string, 0)
+	current := make([]string, 0)
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				paragraphs = append(paragraphs, current)
+				current = make([]string, 0)
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		paragraphs = append(paragraphs, current)
+	}
+
+	return paragraphs
+}
+
+// truncateWords cuts text to at most maxLength characters at a word boundary
+// and appends an ellipsis. A maxLength of zero or less disables truncation.
+func truncateWords(text string, maxLength int) string {
+	runes := []rune(text)
+	if maxLength <= 0 || len(runes) <= maxLength {
+		return text
+	}
+
+	cut := maxLength
+	for cut > maxLength/2 && !unicode.IsSpace(runes[cut]) {
+		cut--
+	}
+	if cut <= maxLength/2 {
+		cut = maxLength
+	}
+
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace) + "…"
+}
+
+// isSafeURL reports whether a link target may be included in an email
+func isSafeURL(url string) bool {
+	lower := strings.ToLower(url)
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")
+}
@@ -0,0 +1,143 @@
+package email
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// eventHandlerPattern matches a tag carrying an on* event handler attribute
+var eventHandlerPattern = regexp.MustCompile(`(?i)<[^>]*\son[a-z]+\s*=`)
+
+func TestRenderDescriptionStripsUnsafeMarkup(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		wantHTML string // Expected to appear in the HTML output
+		wantText string // Expected plain text output
+	}{
+		{
+			name:     "script tag",
+			source:   "Before<script>alert(1)</script>After",
+			wantHTML: "<p>Beforealert(1)After</p>",
+			wantText: "Beforealert(1)After",
+		},
+		{
+			name:     "escaped script tag",
+			source:   "&lt;script&gt;alert(1)&lt;/script&gt;",
+			wantHTML: "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>",
+			wantText: "<script>alert(1)</script>",
+		},
+		{
+			name:     "event handler on tag",
+			source:   `<img src="x" onerror="alert(1)">Cover <b onclick="alert(1)">bold</b>`,
+			wantHTML: "<p>Cover bold</p>",
+			wantText: "Cover bold",
+		},
+		{
+			name:     "escaped event handler",
+			source:   `&lt;img src=x onerror=alert(1)&gt;`,
+			wantHTML: "<p>&lt;img src=x onerror=alert(1)&gt;</p>",
+			wantText: "<img src=x onerror=alert(1)>",
+		},
+		{
+			name:     "javascript markdown link",
+			source:   "[Read](javascript:alert`1`)",
+			wantHTML: "<p>Read</p>",
+			wantText: "Read",
+		},
+		{
+			name:     "javascript bbcode link",
+			source:   "[url=JavaScript:alert`1`]Read[/url]",
+			wantHTML: "<p>Read</p>",
+			wantText: "Read",
+		},
+		{
+			name:     "javascript link in html",
+			source:   `<a href="javascript:alert(1)">Read</a>`,
+			wantHTML: "<p>Read</p>",
+			wantText: "Read",
+		},
+		{
+			name:     "safe link",
+			source:   "[Raw](https://example.com/raw)",
+			wantHTML: `<p><a href="https://example.com/raw">Raw</a></p>`,
+			wantText: "Raw (https://example.com/raw)",
+		},
+		{
+			name:     "quote in link url",
+			source:   `[Raw](https://example.com/"onmouseover="alert)`,
+			wantHTML: `<p><a href="https://example.com/&#34;onmouseover=&#34;alert">Raw</a></p>`,
+			wantText: `Raw (https://example.com/"onmouseover="alert)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, text := RenderDescription(tt.source, 0)
+
+			if !strings.Contains(string(html), tt.wantHTML) {
+				t.Errorf("HTML = %q, want it to contain %q", html, tt.wantHTML)
+			}
+			if text != tt.wantText {
+				t.Errorf("text = %q, want %q", text, tt.wantText)
+			}
+
+			lower := strings.ToLower(string(html))
+			if strings.Contains(lower, "<script") || strings.Contains(lower, "<img") {
+				t.Errorf("HTML %q contains a tag that is not allowed", html)
+			}
+			if strings.Contains(lower, `href="javascript:`) {
+				t.Errorf("HTML %q contains a javascript: link", html)
+			}
+			if eventHandlerPattern.MatchString(string(html)) {
+				t.Errorf("HTML %q contains an event handler", html)
+			}
+		})
+	}
+}
+
+func TestRenderDescriptionMarkup(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		wantHTML string
+		wantText string
+	}{
+		{"markdown emphasis", "**Bold** and *italic*", "<p><strong>Bold</strong> and <em>italic</em></p>", "Bold and italic"},
+		{"bbcode emphasis", "[b]Bold[/b] and [i]italic[/i]", "<p><strong>Bold</strong> and <em>italic</em></p>", "Bold and italic"},
+		{"list", "- One\n- Two", "<ul><li>One</li><li>Two</li></ul>", "- One\n- Two"},
+		{"paragraphs", "First\n\nSecond", "<p>First</p><p>Second</p>", "First\n\nSecond"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, text := RenderDescription(tt.source, 0)
+			if string(html) != tt.wantHTML {
+				t.Errorf("HTML = %q, want %q", html, tt.wantHTML)
+			}
+			if text != tt.wantText {
+				t.Errorf("text = %q, want %q", text, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestTruncateWords(t *testing.T) {
+	tests := []struct {
+		text      string
+		maxLength int
+		want      string
+	}{
+		{"short text", 0, "short text"},
+		{"short text", 20, "short text"},
+		{"one two three four", 10, "one two…"},
+		{"abcdefghijklmnop", 8, "abcdefgh…"},
+	}
+
+	for _, tt := range tests {
+		if got := truncateWords(tt.text, tt.maxLength); got != tt.want {
+			t.Errorf("truncateWords(%q, %d) = %q, want %q", tt.text, tt.maxLength, got, tt.want)
+		}
+	}
+}
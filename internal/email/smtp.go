@@ -0,0 +1,527 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mangadex-cli/internal/config"
+)
+
+// SMTP security modes
+const (
+	SecuritySSL      = "ssl"      // Implicit TLS from the first byte, usually port 465
+	SecuritySTARTTLS = "starttls" // Plain connection upgraded with STARTTLS, usually port 587
+	SecurityNone     = "none"     // Plaintext, for local relays only
+)
+
+// SMTP auth mechanisms
+const (
+	AuthPlain   = "plain"
+	AuthLogin   = "login"
+	AuthCRAMMD5 = "cram-md5"
+	AuthXOAUTH2 = "xoauth2"
+)
+
+// smtpTimeout bounds connecting to the server
+const smtpTimeout = 10 * time.Second
+
+// smtpCommandTimeout bounds each command of a session, including sending a
+// message's body, so a stalled server cannot hang the sender
+const smtpCommandTimeout = 30 * time.Second
+
+// smtpQuitTimeout bounds saying goodbye to a server that may be gone
+const smtpQuitTimeout = 5 * time.Second
+
+// SecurityMode returns the configured security mode. Configurations written
+// before modes existed only set UseTLS, which is read as implicit TLS on
+// port 465 and STARTTLS on any other port.
+func SecurityMode(settings config.SMTPConfig) string {
+	if settings.Security != "" {
+		return strings.ToLower(settings.Security)
+	}
+	switch {
+	case settings.UseTLS && settings.Port == 465:
+		return SecuritySSL
+	case settings.UseTLS:
+		return SecuritySTARTTLS
+	default:
+		return SecurityNone
+	}
+}
+
+// CheckCredentialSecurity rejects settings that would send a password in the
+// clear: a username with security mode none, unless the server is local or
+// CRAM-MD5, which never sends the password, is configured
+func CheckCredentialSecurity(settings config.SMTPConfig) error {
+	if settings.Username == "" || SecurityMode(settings) != SecurityNone || isLocalhost(settings.Server) {
+		return nil
+	}
+	if strings.ToLower(settings.AuthMechanism) == AuthCRAMMD5 {
+		return nil
+	}
+	return fmt.Errorf("security mode none cannot be used with a username, as credentials would be sent unencrypted to %s; use ssl or starttls, or clear the username for a relay without authentication", settings.Server)
+}
+
+// smtpTransport delivers messages over an SMTP session that is kept open
+// between messages. Sessions are replaced once they reach the configured
+// message cap, and a reused session that fails is replaced and the message
+// sent again, as servers drop idle connections.
+type smtpTransport struct {
+	settings config.SMTPConfig
+	tokens   *TokenSource // Nil unless OAuth is configured
+
+	mu          sync.Mutex // Guards the session
+	sender      *smtpSender
+	sessionSent int // Messages sent over the current session
+	info        string
+}
+
+// newSMTPTransport creates an SMTP transport; it connects on first use
+func newSMTPTransport(settings config.SMTPConfig) *smtpTransport {
+	t := &smtpTransport{settings: settings}
+	if settings.OAuth.ClientID != "" {
+		t.tokens = NewTokenSource(settings.OAuth)
+	}
+	return t
+}
+
+// Connect opens a new SMTP session
+func (t *smtpTransport) Connect(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.dial(ctx)
+}
+
+// Close ends the SMTP session, if one is open
+func (t *smtpTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.hangUp()
+}
+
+// Describe returns the server and what the last connection negotiated
+func (t *smtpTransport) Describe() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	server := net.JoinHostPort(t.settings.Server, strconv.Itoa(t.settings.Port))
+	if t.info == "" {
+		return "SMTP " + server
+	}
+	return fmt.Sprintf("SMTP %s (%s)", server, t.info)
+}
+
+// Send delivers a message over the open session, connecting first if needed.
+// If the context is done before the server accepted the message, the session
+// is abandoned and the context's error returned.
+func (t *smtpTransport) Send(ctx context.Context, from string, to []string, msg io.WriterTo) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.sender != nil && t.settings.MaxMessagesPerConnection > 0 && t.sessionSent >= t.settings.MaxMessagesPerConnection {
+		t.hangUp()
+	}
+
+	reused := t.sender != nil
+	if !reused {
+		if err := t.dial(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Only retry if the server cannot have accepted the message, or it would
+	// be delivered twice
+	err := t.sender.Send(ctx, from, to, msg)
+	var dataErr *dataError
+	if err != nil && reused && ctx.Err() == nil && !errors.As(err, &dataErr) {
+		if err = t.dial(ctx); err == nil {
+			err = t.sender.Send(ctx, from, to, msg)
+		}
+	}
+	if err != nil {
+		// The session may be mid-transaction, so start over next time
+		t.hangUp()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+
+	t.sessionSent++
+	return nil
+}
+
+// dial opens a new SMTP session, closing any open one first
+func (t *smtpTransport) dial(ctx context.Context) error {
+	t.hangUp()
+
+	sender, info, err := dialSMTP(ctx, t.settings, t.tokens)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to connect to email server: %w", err)
+	}
+
+	t.sender = sender
+	t.sessionSent = 0
+	t.info = info
+	return nil
+}
+
+// hangUp closes the SMTP session, if one is open
+func (t *smtpTransport) hangUp() error {
+	if t.sender == nil {
+		return nil
+	}
+
+	err := t.sender.Close()
+	t.sender = nil
+	t.sessionSent = 0
+	return err
+}
+
+// smtpSender sends messages over one SMTP session
+type smtpSender struct {
+	client *smtp.Client
+	conn   net.Conn
+}
+
+// dataError is a failure after the server agreed to take the message body,
+// when the message may have been delivered anyway
+type dataError struct {
+	err error
+}
+
+func (e *dataError) Error() string { return e.err.Error() }
+
+func (e *dataError) Unwrap() error { return e.err }
+
+// Send sends one message in the session. Failures once the body is being
+// sent are returned as a *dataError.
+func (s *smtpSender) Send(ctx context.Context, from string, to []string, msg io.WriterTo) error {
+	defer watchContext(ctx, s.conn)()
+
+	if err := s.extendDeadline(ctx); err != nil {
+		return err
+	}
+	if err := s.client.Mail(from); err != nil {
+		return err
+	}
+	for _, address := range to {
+		if err := s.extendDeadline(ctx); err != nil {
+			return err
+		}
+		if err := s.client.Rcpt(address); err != nil {
+			return err
+		}
+	}
+
+	if err := s.extendDeadline(ctx); err != nil {
+		return err
+	}
+	w, err := s.client.Data()
+	if err != nil {
+		return err
+	}
+	if err := s.extendDeadline(ctx); err != nil {
+		w.Close()
+		return &dataError{err}
+	}
+	if _, err := msg.WriteTo(w); err != nil {
+		w.Close()
+		return &dataError{err}
+	}
+	if err := w.Close(); err != nil {
+		return &dataError{err}
+	}
+	return nil
+}
+
+// extendDeadline gives the next command smtpCommandTimeout to complete,
+// unless the context is already done. The context is checked after the
+// deadline is set, so a cancellation cannot be overwritten.
+func (s *smtpSender) extendDeadline(ctx context.Context) error {
+	s.conn.SetDeadline(time.Now().Add(smtpCommandTimeout))
+	return ctx.Err()
+}
+
+// Close ends the session. The connection is closed even if the server does
+// not answer QUIT, as happens when the session has broken.
+func (s *smtpSender) Close() error {
+	s.conn.SetDeadline(time.Now().Add(smtpQuitTimeout))
+	err := s.client.Quit()
+
+	// A successful QUIT has already closed the connection
+	if closeErr := s.client.Close(); err == nil && closeErr != nil && !errors.Is(closeErr, net.ErrClosed) {
+		err = closeErr
+	}
+	return err
+}
+
+// watchContext interrupts reads and writes on conn once the context is done,
+// until the returned function is called
+func watchContext(ctx context.Context, conn net.Conn) func() {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Unix(1, 0))
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+// dialSMTP opens an authenticated SMTP session using the configured security
+// mode, and describes what was negotiated
+func dialSMTP(ctx context.Context, settings config.SMTPConfig, tokens *TokenSource) (*smtpSender, string, error) {
+	tlsConfig, err := smtpTLSConfig(settings)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := CheckCredentialSecurity(settings); err != nil {
+		return nil, "", err
+	}
+
+	addr := net.JoinHostPort(settings.Server, strconv.Itoa(settings.Port))
+	mode := SecurityMode(settings)
+
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	var conn net.Conn
+	switch mode {
+	case SecuritySSL:
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	case SecuritySTARTTLS, SecurityNone:
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	default:
+		return nil, "", fmt.Errorf("unknown SMTP security mode %q, must be ssl, starttls or none", mode)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The greeting, STARTTLS and authentication can be interrupted too, and
+	// must not take longer than connecting may
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+	defer watchContext(ctx, conn)()
+
+	client, err := smtp.NewClient(conn, settings.Server)
+	if err != nil {
+		conn.Close()
+		return nil, "", err
+	}
+
+	if mode == SecuritySTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, "", fmt.Errorf("server does not support STARTTLS; use security mode ssl or none")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, "", fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+
+	parts := []string{strings.ToUpper(mode)}
+	if state, ok := client.TLSConnectionState(); ok {
+		parts = append(parts, tlsVersionName(state.Version))
+	}
+
+	if settings.Username != "" {
+		auth, mechanism, err := smtpAuth(ctx, client, settings, tokens)
+		if err != nil {
+			client.Close()
+			return nil, "", err
+		}
+		if err := client.Auth(auth); err != nil {
+			client.Close()
+			if mechanism == AuthXOAUTH2 && tokens != nil {
+				// The token may have been revoked; get a new one next time
+				tokens.Invalidate()
+			}
+			return nil, "", fmt.Errorf("%s authentication failed: %w", strings.ToUpper(mechanism), err)
+		}
+		parts = append(parts, "AUTH "+strings.ToUpper(mechanism))
+	}
+
+	return &smtpSender{client: client, conn: conn}, strings.Join(parts, ", "), nil
+}
+
+// smtpTLSConfig builds the TLS settings, trusting the configured CA bundle in
+// addition to the system roots
+func smtpTLSConfig(settings config.SMTPConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         settings.Server,
+		InsecureSkipVerify: settings.InsecureSkipVerify,
+	}
+
+	if settings.CAFile != "" {
+		pem, err := os.ReadFile(settings.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", settings.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// smtpAuth picks the configured auth mechanism, XOAUTH2 if OAuth is set up,
+// or PLAIN or LOGIN if the server offers them. Sessions carrying credentials
+// are encrypted, so these are safe; the deprecated CRAM-MD5 is only used if
+// the server offers nothing else, as servers storing hashed passwords cannot
+// check it.
+func smtpAuth(ctx context.Context, client *smtp.Client, settings config.SMTPConfig, tokens *TokenSource) (smtp.Auth, string, error) {
+	mechanism := strings.ToLower(settings.AuthMechanism)
+	if mechanism == "" && tokens != nil {
+		mechanism = AuthXOAUTH2
+	}
+	if mechanism == "" {
+		_, offered := client.Extension("AUTH")
+		offered = strings.ToUpper(offered)
+		mechanisms := strings.Fields(offered)
+		switch {
+		case containsString(mechanisms, "PLAIN"):
+			mechanism = AuthPlain
+		case containsString(mechanisms, "LOGIN"):
+			mechanism = AuthLogin
+		case containsString(mechanisms, "CRAM-MD5"):
+			mechanism = AuthCRAMMD5
+		default:
+			mechanism = AuthPlain
+		}
+	}
+
+	switch mechanism {
+	case AuthPlain:
+		return smtp.PlainAuth("", settings.Username, settings.Password, settings.Server), mechanism, nil
+	case AuthLogin:
+		return &loginAuth{username: settings.Username, password: settings.Password, host: settings.Server}, mechanism, nil
+	case AuthCRAMMD5:
+		return smtp.CRAMMD5Auth(settings.Username, settings.Password), mechanism, nil
+	case AuthXOAUTH2:
+		// Without OAuth settings the password is used as the access token
+		token := settings.Password
+		if tokens != nil {
+			var err error
+			if token, err = tokens.TokenContext(ctx); err != nil {
+				return nil, "", err
+			}
+		}
+		return &xoauth2Auth{username: settings.Username, token: token}, mechanism, nil
+	default:
+		return nil, "", fmt.Errorf("unknown SMTP auth mechanism %q, must be plain, login, cram-md5 or xoauth2", mechanism)
+	}
+}
+
+// loginAuth implements the LOGIN mechanism, which net/smtp does not provide
+type loginAuth struct {
+	username, password, host string
+}
+
+// Start begins LOGIN authentication, refusing to send the password in the clear
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+// Next answers the username and password prompts
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	prompt := strings.ToLower(string(fromServer))
+	switch {
+	case strings.Contains(prompt, "username"):
+		return []byte(a.username), nil
+	case strings.Contains(prompt, "password"):
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected LOGIN prompt %q", fromServer)
+	}
+}
+
+// xoauth2Auth implements the XOAUTH2 mechanism with a bearer access token
+type xoauth2Auth struct {
+	username, token string
+}
+
+// Start sends the username and access token as the initial response
+func (a *xoauth2Auth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	return "XOAUTH2", []byte("user=" + a.username + "\x01auth=Bearer " + a.token + "\x01\x01"), nil
+}
+
+// Next acknowledges the error details the server sends on failure, so it
+// reports the failure
+func (a *xoauth2Auth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		return []byte{}, nil
+	}
+	return nil, nil
+}
+
+// containsString reports whether a list contains a value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// isLocalhost reports whether a host name is the loopback interface
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
+
+// tlsVersionName names a TLS protocol version
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("TLS 0x%04x", version)
+	}
+}
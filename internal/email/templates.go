@@ -1,11 +1,338 @@
 package email
 
-// This file exists for potential future extension of notification templates
-// Currently, the email templates are implemented directly in email.go
-// This file serves as a placeholder for more complex template handling in the future
-
-// Potential future enhancements:
-// - Template loading from files
-// - Customizable templates through configuration
-// - Template language/localization support
-// - Multiple template styles (minimal, detailed, etc.)
\ No newline at end of file
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"mangadex-cli/internal/api"
+)
+
+// Notification emails are rendered from a pair of templates per kind: an
+// html/template for the HTML body and a text/template for the plain text
+// alternative. The defaults are embedded in the binary. Any of them can be
+// overridden by placing a file with the same name in the configured template
+// directory:
+//
+//	notification.html.tmpl, notification.txt.tmpl  (NotificationData)
+//	digest.html.tmpl,       digest.txt.tmpl        (DigestData)
+//	test.html.tmpl,         test.txt.tmpl          (TestData)
+//
+// Besides the built-in template functions, templates can use:
+//
+//	date  formats a time as "January 2, 2006"
+//	join  joins a list of strings with a separator
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// Template kinds
+const (
+	TemplateNotification = "notification"
+	TemplateDigest       = "digest"
+	TemplateTest         = "test"
+)
+
+var templateKinds = []string{TemplateNotification, TemplateDigest, TemplateTest}
+
+// Recipient is the user an email is sent to
+type Recipient struct {
+	ID    int // User ID, for unsubscribe links; zero if the recipient is not a user
+	Email string
+	Name  string
+}
+
+// TemplateManga describes a series
+type TemplateManga struct {
+	ID              string
+	Title           string
+	Description     string            // Plain text, markup removed
+	DescriptionHTML htmltemplate.HTML // Sanitized HTML converted from Markdown/BBCode
+	Status          string
+	URL             string // MangaDex title page
+	CoverURL        htmltemplate.URL // Inline "cid:" image when embedded, else the remote thumbnail; empty if the series has no cover
+}
+
+// TemplateChapter describes a chapter
+type TemplateChapter struct {
+	ID        string
+	Number    string // Chapter number as published, e.g. "10.5"; empty for oneshots and volume-only releases
+	Label     string // Short name, e.g. "Ch. 10.5", "Ch. 21–24", "Vol. 3" or "Oneshot"
+	Volume    string
+	Title     string
+	Language  string
+	Groups    []string // Scanlation groups
+	URL       string   // MangaDex reader page
+	PublishAt time.Time
+}
+
+// TemplateVolume is a run of chapters from the same volume
+type TemplateVolume struct {
+	Volume   string // Empty for chapters without a volume
+	Label    string // Summary, e.g. "Vol. 3: Ch. 21–24"
+	Chapters []TemplateChapter
+}
+
+// NotificationData is passed to notification templates: new chapters of one series
+type NotificationData struct {
+	User              Recipient
+	Manga             TemplateManga
+	Chapters          []TemplateChapter // Sorted by volume and chapter number
+	Volumes           []TemplateVolume  // Chapters grouped by volume
+	Groups            []string          // Every scanlation group across Chapters
+	UnsubscribeURL    string            // Stops notifications for this manga; empty if unsubscribe links are not configured
+	UnsubscribeAllURL string            // Stops every notification to the user
+	SentAt            time.Time
+}
+
+// DigestSeries is one series section of a digest
+type DigestSeries struct {
+	Manga    TemplateManga
+	Chapters []TemplateChapter // Sorted by volume and chapter number
+	Volumes  []TemplateVolume  // Chapters grouped by volume
+	Groups   []string
+}
+
+// DigestData is passed to digest templates: new chapters across several series
+type DigestData struct {
+	User           Recipient
+	Series         []DigestSeries
+	TotalChapters  int
+	UnsubscribeURL string // Stops every notification to the user; empty if unsubscribe links are not configured
+	SentAt         time.Time
+}
+
+// TestData is passed to test email templates
+type TestData struct {
+	User   Recipient
+	SentAt time.Time
+}
+
+// templateFuncs are available in both HTML and text templates
+var templateFuncs = map[string]interface{}{
+	"date": func(t time.Time) string { return t.Format("January 2, 2006") },
+	"join": strings.Join,
+}
+
+// Templates holds the parsed email templates
+type Templates struct {
+	html map[string]*htmltemplate.Template
+	text map[string]*texttemplate.Template
+}
+
+// LoadTemplates parses the embedded default templates, replacing any that
+// exist in dir. An empty dir loads only the defaults.
+func LoadTemplates(dir string) (*Templates, error) {
+	t := &Templates{
+		html: make(map[string]*htmltemplate.Template),
+		text: make(map[string]*texttemplate.Template),
+	}
+
+	for _, kind := range templateKinds {
+		htmlName := kind + ".html.tmpl"
+		source, err := readTemplate(dir, htmlName)
+		if err != nil {
+			return nil, err
+		}
+		htmlTmpl, err := htmltemplate.New(htmlName).Funcs(templateFuncs).Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", htmlName, err)
+		}
+		t.html[kind] = htmlTmpl
+
+		textName := kind + ".txt.tmpl"
+		source, err = readTemplate(dir, textName)
+		if err != nil {
+			return nil, err
+		}
+		textTmpl, err := texttemplate.New(textName).Funcs(templateFuncs).Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", textName, err)
+		}
+		t.text[kind] = textTmpl
+	}
+
+	return t, nil
+}
+
+// readTemplate reads a template override from dir, or the embedded default
+func readTemplate(dir, name string) (string, error) {
+	if dir != "" {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			return string(data), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read template %s: %w", name, err)
+		}
+	}
+
+	data, err := defaultTemplates.ReadFile("templates/" + name)
+	if err != nil {
+		return "", fmt.Errorf("failed to read default template %s: %w", name, err)
+	}
+	return string(data), nil
+}
+
+// Render executes the HTML and text templates of a kind
+func (t *Templates) Render(kind string, data interface{}) (string, string, error) {
+	htmlTmpl, ok := t.html[kind]
+	if !ok {
+		return "", "", fmt.Errorf("unknown template: %s", kind)
+	}
+
+	var html, text bytes.Buffer
+	if err := htmlTmpl.Execute(&html, data); err != nil {
+		return "", "", fmt.Errorf("failed to render %s HTML template: %w", kind, err)
+	}
+	if err := t.text[kind].Execute(&text, data); err != nil {
+		return "", "", fmt.Errorf("failed to render %s text template: %w", kind, err)
+	}
+
+	return html.String(), text.String(), nil
+}
+
+// newTemplateManga converts a manga to its template model, truncating the
+// description to descriptionLength characters
+func newTemplateManga(manga *api.Manga, descriptionLength int) TemplateManga {
+	descriptionHTML, description := RenderDescription(manga.GetDescription(), descriptionLength)
+
+	return TemplateManga{
+		ID:              manga.ID,
+		Title:           manga.GetTitle(),
+		Description:     description,
+		DescriptionHTML: descriptionHTML,
+		Status:          manga.Status,
+		URL:             fmt.Sprintf("https://mangadex.org/title/%s", manga.ID),
+		CoverURL:        htmltemplate.URL(manga.CoverArtURL),
+	}
+}
+
+// newTemplateChapters converts chapters to their template model, sorted and
+// grouped by volume, and collects every scanlation group across them
+func newTemplateChapters(chapters []api.Chapter) ([]TemplateChapter, []TemplateVolume, []string) {
+	result := make([]TemplateChapter, 0, len(chapters))
+	volumes := make([]TemplateVolume, 0)
+	groups := make([]string, 0)
+	seenGroups := make(map[string]bool)
+
+	for _, volume := range api.GroupByVolume(chapters) {
+		templateVolume := TemplateVolume{Volume: volume.Volume, Label: volume.Label()}
+
+		for _, chapter := range volume.Chapters {
+			templateChapter := TemplateChapter{
+				ID:        chapter.ID,
+				Number:    chapter.Chapter,
+				Label:     chapter.Number().Label(),
+				Volume:    chapter.Volume,
+				Title:     chapter.Title,
+				Language:  chapter.TranslatedLanguage,
+				Groups:    chapter.GetGroupNames(),
+				URL:       fmt.Sprintf("https://mangadex.org/chapter/%s", chapter.ID),
+				PublishAt: chapter.PublishAt,
+			}
+			result = append(result, templateChapter)
+			templateVolume.Chapters = append(templateVolume.Chapters, templateChapter)
+
+			for _, group := range templateChapter.Groups {
+				if !seenGroups[group] {
+					seenGroups[group] = true
+					groups = append(groups, group)
+				}
+			}
+		}
+
+		volumes = append(volumes, templateVolume)
+	}
+
+	return result, volumes, groups
+}
+
+// SampleData returns example data for a template kind, for previewing templates
+func SampleData(kind string) (interface{}, error) {
+	now := time.Now()
+	user := Recipient{Email: "reader@example.com", Name: "Example Reader"}
+	descriptionHTML, description := RenderDescription(
+		"A **sample** series used to preview notification templates.\n\n"+
+			"---\n- [Official site](https://example.com)", DefaultDescriptionLength)
+	manga := TemplateManga{
+		ID:              "a1c7c817-4e59-43b7-9365-09675a149a6f",
+		Title:           "Example Manga",
+		Description:     description,
+		DescriptionHTML: descriptionHTML,
+		Status:          "ongoing",
+		URL:             "https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f",
+	}
+	chapters, volumes, groups := newTemplateChapters([]api.Chapter{
+		{
+			ID:                 "00000000-0000-0000-0000-000000000002",
+			Chapter:            "41.5",
+			Volume:             "5",
+			Title:              "Extra",
+			TranslatedLanguage: "en",
+			Groups:             []string{"00000000-0000-0000-0000-00000000000a"},
+			GroupNames:         []string{"Example Scans"},
+			PublishAt:          now.Add(-1 * time.Hour),
+		},
+		{
+			ID:                 "00000000-0000-0000-0000-000000000001",
+			Chapter:            "41",
+			Volume:             "5",
+			Title:              "The Journey Begins",
+			TranslatedLanguage: "en",
+			Groups:             []string{"00000000-0000-0000-0000-00000000000a"},
+			GroupNames:         []string{"Example Scans"},
+			PublishAt:          now.Add(-2 * time.Hour),
+		},
+		{
+			ID:                 "00000000-0000-0000-0000-000000000003",
+			Chapter:            "42",
+			Title:              "Homecoming",
+			TranslatedLanguage: "en",
+			Groups:             []string{"00000000-0000-0000-0000-00000000000a"},
+			GroupNames:         []string{"Example Scans"},
+			PublishAt:          now.Add(-30 * time.Minute),
+		},
+	})
+	unsubscribeURL := "https://notifier.example.com/unsubscribe?token=sample"
+	unsubscribeAllURL := "https://notifier.example.com/unsubscribe?token=sample-all"
+
+	switch kind {
+	case TemplateNotification:
+		return NotificationData{
+			User:              user,
+			Manga:             manga,
+			Chapters:          chapters,
+			Volumes:           volumes,
+			Groups:            groups,
+			UnsubscribeURL:    unsubscribeURL,
+			UnsubscribeAllURL: unsubscribeAllURL,
+			SentAt:            now,
+		}, nil
+	case TemplateDigest:
+		second := manga
+		second.ID = "b2d8d928-5f6a-54c8-a476-1a786b25ab70"
+		second.Title = "Another Example"
+		second.URL = "https://mangadex.org/title/" + second.ID
+		return DigestData{
+			User: user,
+			Series: []DigestSeries{
+				{Manga: manga, Chapters: chapters, Volumes: volumes, Groups: groups},
+				{Manga: second, Chapters: chapters[:1], Volumes: []TemplateVolume{{Volume: "5", Label: "Vol. 5: Ch. 41", Chapters: chapters[:1]}}, Groups: groups},
+			},
+			TotalChapters:  len(chapters) + 1,
+			UnsubscribeURL: unsubscribeAllURL,
+			SentAt:         now,
+		}, nil
+	case TemplateTest:
+		return TestData{User: user, SentAt: now}, nil
+	default:
+		return nil, fmt.Errorf("unknown template: %s", kind)
+	}
+}
@@ -0,0 +1,275 @@
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"mangadex-cli/internal/config"
+)
+
+// Transport delivers rendered messages. Besides SMTP, messages can be piped
+// to a sendmail-compatible binary or written to a Maildir or mbox file; the
+// file sinks need no network at all.
+type Transport interface {
+	// Connect prepares the transport, such as opening an SMTP session
+	Connect(ctx context.Context) error
+
+	// Send delivers one message from an envelope sender to its recipients.
+	// Once the context is done, delivery is abandoned if it has not finished.
+	Send(ctx context.Context, from string, to []string, msg io.WriterTo) error
+
+	// Close releases anything held between messages
+	Close() error
+
+	// Describe summarizes where messages are delivered
+	Describe() string
+}
+
+// Transport types
+const (
+	TransportSMTP     = "smtp"
+	TransportSendmail = "sendmail"
+	TransportMaildir  = "maildir"
+	TransportMbox     = "mbox"
+)
+
+// DefaultSendmailPath is where sendmail-compatible binaries are usually installed
+const DefaultSendmailPath = "/usr/sbin/sendmail"
+
+// NewTransport creates the configured transport
+func NewTransport(mail config.MailConfig, smtp config.SMTPConfig) (Transport, error) {
+	switch strings.ToLower(mail.Transport) {
+	case "", TransportSMTP:
+		return newSMTPTransport(smtp), nil
+	case TransportSendmail:
+		path := mail.SendmailPath
+		if path == "" {
+			path = DefaultSendmailPath
+		}
+		return &sendmailTransport{path: path}, nil
+	case TransportMaildir:
+		if mail.Path == "" {
+			return nil, fmt.Errorf("maildir transport needs a directory path")
+		}
+		return &maildirTransport{dir: mail.Path}, nil
+	case TransportMbox:
+		if mail.Path == "" {
+			return nil, fmt.Errorf("mbox transport needs a file path")
+		}
+		return &mboxTransport{path: mail.Path}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport %q, must be smtp, sendmail, maildir or mbox", mail.Transport)
+	}
+}
+
+// sendmailTransport pipes each message to a sendmail-compatible binary
+type sendmailTransport struct {
+	path string
+}
+
+// Connect checks that the binary exists
+func (t *sendmailTransport) Connect(ctx context.Context) error {
+	if _, err := exec.LookPath(t.path); err != nil {
+		return fmt.Errorf("sendmail binary not found: %w", err)
+	}
+	return nil
+}
+
+// Send runs the binary with the envelope on the command line and the message
+// on standard input. The process is killed if the context is done first.
+func (t *sendmailTransport) Send(ctx context.Context, from string, to []string, msg io.WriterTo) error {
+	var message bytes.Buffer
+	if _, err := msg.WriteTo(&message); err != nil {
+		return err
+	}
+
+	// -i stops a line with a single dot from ending the message early
+	args := append([]string{"-i", "-f", from, "--"}, to...)
+	cmd := exec.CommandContext(ctx, t.path, args...)
+	cmd.Stdin = &message
+
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("%s failed: %w: %s", t.path, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Close does nothing, as every message runs its own process
+func (t *sendmailTransport) Close() error {
+	return nil
+}
+
+// Describe returns the binary messages are piped to
+func (t *sendmailTransport) Describe() string {
+	return "sendmail " + t.path
+}
+
+// maildirTransport writes each message as a new .eml file in a Maildir
+type maildirTransport struct {
+	dir string
+
+	mu       sync.Mutex
+	sequence int
+}
+
+// Connect creates the Maildir if it does not exist
+func (t *maildirTransport) Connect(ctx context.Context) error {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(t.dir, sub), 0700); err != nil {
+			return fmt.Errorf("failed to create Maildir: %w", err)
+		}
+	}
+	return nil
+}
+
+// Send writes the message to tmp and moves it into new, so readers never see
+// a partly written file
+func (t *maildirTransport) Send(ctx context.Context, from string, to []string, msg io.WriterTo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := t.Connect(ctx); err != nil {
+		return err
+	}
+
+	name := t.uniqueName() + ".eml"
+	tmpPath := filepath.Join(t.dir, "tmp", name)
+
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create message file: %w", err)
+	}
+	if _, err := msg.WriteTo(file); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write message file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write message file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, filepath.Join(t.dir, "new", name)); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to deliver message file: %w", err)
+	}
+	return nil
+}
+
+// uniqueName returns a Maildir file name: time, process and host
+func (t *maildirTransport) uniqueName() string {
+	t.mu.Lock()
+	t.sequence++
+	sequence := t.sequence
+	t.mu.Unlock()
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	// "/" and ":" have a meaning in Maildir file names
+	host = strings.NewReplacer("/", "\\057", ":", "\\072").Replace(host)
+
+	now := time.Now()
+	return fmt.Sprintf("%d.M%dP%dQ%d.%s", now.Unix(), now.Nanosecond()/1000, os.Getpid(), sequence, host)
+}
+
+// Close does nothing, as every message is its own file
+func (t *maildirTransport) Close() error {
+	return nil
+}
+
+// Describe returns the Maildir messages are written to
+func (t *maildirTransport) Describe() string {
+	return "Maildir " + t.dir
+}
+
+// mboxTransport appends each message to an mbox file
+type mboxTransport struct {
+	path string
+
+	mu sync.Mutex // Keeps appended messages whole
+}
+
+// Connect creates the mbox file if it does not exist
+func (t *mboxTransport) Connect(ctx context.Context) error {
+	if err := os.MkdirAll(filepath.Dir(t.path), 0700); err != nil {
+		return fmt.Errorf("failed to create mbox directory: %w", err)
+	}
+	file, err := os.OpenFile(t.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open mbox: %w", err)
+	}
+	return file.Close()
+}
+
+// Send appends the message after a "From " separator line. Lines starting
+// with "From " are quoted with ">" (mboxrd), and line endings are converted
+// to LF.
+func (t *mboxTransport) Send(ctx context.Context, from string, to []string, msg io.WriterTo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var message bytes.Buffer
+	if _, err := msg.WriteTo(&message); err != nil {
+		return err
+	}
+
+	var entry bytes.Buffer
+	fmt.Fprintf(&entry, "From %s %s\n", from, time.Now().UTC().Format(time.ANSIC))
+
+	scanner := bufio.NewScanner(&message)
+	scanner.Buffer(make([]byte, 64*1024), message.Len()+1)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.HasPrefix(strings.TrimLeft(line, ">"), "From ") {
+			line = ">" + line
+		}
+		entry.WriteString(line)
+		entry.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	entry.WriteByte('\n')
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.Connect(ctx); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(t.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open mbox: %w", err)
+	}
+	if _, err := file.Write(entry.Bytes()); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to append to mbox: %w", err)
+	}
+	return file.Close()
+}
+
+// Close does nothing, as the file is opened for every message
+func (t *mboxTransport) Close() error {
+	return nil
+}
+
+// Describe returns the mbox file messages are appended to
+func (t *mboxTransport) Describe() string {
+	return "mbox " + t.path
+}
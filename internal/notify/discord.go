@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"mangadex-cli/internal/api"
+	"mangadex-cli/internal/config"
+	"mangadex-cli/internal/db"
+)
+
+// discordColor is the embed accent color (MangaDex orange)
+const discordColor = 0xFF6740
+
+// Discord limits for embed text, in characters
+const (
+	discordMaxEmbedTitle = 256
+	discordMaxEmbedText  = 4096
+)
+
+// DiscordNotifier posts updates as embeds to a Discord channel webhook
+type DiscordNotifier struct {
+	webhookURL string
+}
+
+// NewDiscordNotifier creates a Discord webhook notifier
+func NewDiscordNotifier(settings config.NotifierConfig) (Notifier, error) {
+	if settings.WebhookURL == "" {
+		return nil, fmt.Errorf("webhook URL must be provided")
+	}
+	return &DiscordNotifier{webhookURL: settings.WebhookURL}, nil
+}
+
+// Type returns the backend type
+func (n *DiscordNotifier) Type() string {
+	return "discord"
+}
+
+// Notify posts one embed listing the new chapters
+func (n *DiscordNotifier) Notify(ctx context.Context, user *db.User, manga *api.Manga, chapters []api.Chapter) error {
+	description := chapterList(chapters,
+		func(label string) string {
+			return fmt.Sprintf("**%s**\n", discordEscape(label))
+		},
+		func(chapter api.Chapter) string {
+			return fmt.Sprintf("[%s](%s) (%s)\n", discordEscape(chapterLabel(chapter)), chapterURL(chapter), discordEscape(chapterSource(chapter)))
+		},
+		discordMaxEmbedText)
+
+	embed := map[string]interface{}{
+		"title":       truncate(fmt.Sprintf("%d new chapter(s) for %s", len(chapters), manga.GetTitle()), discordMaxEmbedTitle),
+		"url":         mangaURL(manga),
+		"description": description,
+		"color":       discordColor,
+	}
+	if manga.CoverArtURL != "" {
+		embed["thumbnail"] = map[string]string{"url": manga.CoverArtURL}
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"username": "MangaDex Notifier",
+		"embeds":   []interface{}{embed},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode Discord payload: %w", err)
+	}
+
+	return postJSON(ctx, n.webhookURL, payload, nil)
+}
+
+// discordEscape escapes Markdown in user-submitted text so it renders literally
+func discordEscape(text string) string {
+	return strings.NewReplacer(
+		"\\", "\\\\", "*", "\\*", "_", "\\_", "~", "\\~", "`", "\\`",
+		"|", "\\|", ">", "\\>", "[", "\\[", "]", "\\]", "(", "\\(", ")", "\\)",
+	).Replace(text)
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"context"
+
+	"mangadex-cli/internal/api"
+	"mangadex-cli/internal/db"
+	"mangadex-cli/internal/email"
+)
+
+// EmailNotifier delivers updates by SMTP to the user's email address
+type EmailNotifier struct {
+	service *email.EmailService
+}
+
+// NewEmailNotifier creates a notifier backed by the email service
+func NewEmailNotifier(service *email.EmailService) *EmailNotifier {
+	return &EmailNotifier{service: service}
+}
+
+// Type returns the backend type
+func (n *EmailNotifier) Type() string {
+	return "email"
+}
+
+// Personal reports that every user is emailed at their own address
+func (n *EmailNotifier) Personal() bool {
+	return true
+}
+
+// Notify sends a notification email to the user
+func (n *EmailNotifier) Notify(ctx context.Context, user *db.User, manga *api.Manga, chapters []api.Chapter) error {
+	return n.service.SendNotificationContext(ctx, recipient(user), manga, chapters)
+}
+
+// NotifyDigest sends one digest email covering every series
+func (n *EmailNotifier) NotifyDigest(ctx context.Context, user *db.User, updates []Update) error {
+	sections := make([]email.DigestSection, 0, len(updates))
+	for _, update := range updates {
+		sections = append(sections, email.DigestSection{Manga: update.Manga, Chapters: update.Chapters})
+	}
+	return n.service.SendDigestContext(ctx, recipient(user), sections)
+}
+
+// Close ends the SMTP session shared by the messages of a delivery run
+func (n *EmailNotifier) Close() error {
+	return n.service.Disconnect()
+}
+
+// recipient converts a user to an email recipient
+func recipient(user *db.User) email.Recipient {
+	return email.Recipient{ID: user.ID, Email: user.Email, Name: user.Name}
+}
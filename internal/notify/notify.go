@@ -0,0 +1,211 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"mangadex-cli/internal/api"
+	"mangadex-cli/internal/config"
+	"mangadex-cli/internal/db"
+	"mangadex-cli/internal/email"
+)
+
+// Notifier delivers a manga update to a user over one channel
+type Notifier interface {
+	// Type returns the backend type, such as "email" or "discord"
+	Type() string
+
+	// Notify sends the new chapters of a manga. A nil error means delivery
+	// was confirmed by the backend.
+	Notify(ctx context.Context, user *db.User, manga *api.Manga, chapters []api.Chapter) error
+}
+
+// Update is one series with its new chapters
+type Update struct {
+	Manga    *api.Manga
+	Chapters []api.Chapter
+}
+
+// DigestNotifier is implemented by notifiers that can deliver updates for
+// several series in a single message. Notifiers without it are sent one
+// message per series when a digest is due.
+type DigestNotifier interface {
+	NotifyDigest(ctx context.Context, user *db.User, updates []Update) error
+}
+
+// PersonalNotifier is implemented by notifiers that deliver to each user's
+// own address, such as email. Other notifiers post to a single destination
+// shared by every user who chose the channel, such as a webhook.
+type PersonalNotifier interface {
+	Personal() bool
+}
+
+// Shared reports whether a notifier posts to one destination for every user
+func Shared(notifier Notifier) bool {
+	personal, ok := notifier.(PersonalNotifier)
+	return !ok || !personal.Personal()
+}
+
+// Factory creates a notifier from its channel settings
+type Factory func(settings config.NotifierConfig) (Notifier, error)
+
+// factories holds the webhook backends that can be configured by type
+var factories = map[string]Factory{
+	"discord": NewDiscordNotifier,
+	"slack":   NewSlackNotifier,
+	"webhook": NewWebhookNotifier,
+}
+
+// RegisterType makes a notifier backend available to configuration
+func RegisterType(typ string, factory Factory) {
+	factories[typ] = factory
+}
+
+// Registry holds the configured notification channels by name
+type Registry struct {
+	notifiers map[string]Notifier
+}
+
+// NewRegistry creates a registry with the built-in email channel and every
+// channel defined in the configuration
+func NewRegistry(emailService *email.EmailService, channels map[string]config.NotifierConfig) (*Registry, error) {
+	registry := &Registry{notifiers: make(map[string]Notifier)}
+	registry.Register(db.DefaultChannel, NewEmailNotifier(emailService))
+
+	for name, settings := range channels {
+		if name == db.DefaultChannel {
+			return nil, fmt.Errorf("channel name %q is reserved", name)
+		}
+
+		factory, ok := factories[settings.Type]
+		if !ok {
+			return nil, fmt.Errorf("channel %q has unknown type %q", name, settings.Type)
+		}
+
+		notifier, err := factory(settings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create channel %q: %w", name, err)
+		}
+		registry.Register(name, notifier)
+	}
+
+	return registry, nil
+}
+
+// Register adds or replaces a named channel
+func (r *Registry) Register(name string, notifier Notifier) {
+	r.notifiers[name] = notifier
+}
+
+// Get returns the notifier for a channel name
+func (r *Registry) Get(name string) (Notifier, bool) {
+	notifier, ok := r.notifiers[name]
+	return notifier, ok
+}
+
+// Names returns the registered channel names in sorted order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.notifiers))
+	for name := range r.notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Close releases connections held by notifiers between messages, such as the
+// SMTP session. It is called at the end of every delivery run.
+func (r *Registry) Close() error {
+	var errs []string
+	for _, name := range r.Names() {
+		if closer, ok := r.notifiers[name].(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to close channels: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// httpClient is shared by the webhook backends
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// postJSON posts a JSON payload to a webhook and checks the response status
+func postJSON(ctx context.Context, url string, payload []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook failed with status code %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// mangaURL returns the MangaDex page of a manga
+func mangaURL(manga *api.Manga) string {
+	return fmt.Sprintf("https://mangadex.org/title/%s", manga.ID)
+}
+
+// chapterURL returns the MangaDex reader page of a chapter
+func chapterURL(chapter api.Chapter) string {
+	return fmt.Sprintf("https://mangadex.org/chapter/%s", chapter.ID)
+}
+
+// chapterLabel returns a short human readable name for a chapter
+func chapterLabel(chapter api.Chapter) string {
+	label := chapter.Number().Label()
+	if chapter.Title != "" {
+		label += " - " + chapter.Title
+	}
+	return label
+}
+
+// chapterList renders chapters sorted and grouped by volume, one heading per
+// volume and one line per chapter, stopping before the text exceeds max bytes
+func chapterList(chapters []api.Chapter, heading func(label string) string, line func(chapter api.Chapter) string, max int) string {
+	var list strings.Builder
+	for _, volume := range api.GroupByVolume(chapters) {
+		lines := []string{heading(volume.Label())}
+		for _, chapter := range volume.Chapters {
+			lines = append(lines, line(chapter))
+		}
+
+		for _, l := range lines {
+			if list.Len()+len(l) > max {
+				return list.String()
+			}
+			list.WriteString(l)
+		}
+	}
+	return list.String()
+}
+
+// chapterSource returns the language and scanlation groups of a chapter,
+// e.g. "en, Example Scans"
+func chapterSource(chapter api.Chapter) string {
+	return strings.Join(append([]string{chapter.TranslatedLanguage}, chapter.GetGroupNames()...), ", ")
+}
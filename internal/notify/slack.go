@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"mangadex-cli/internal/api"
+	"mangadex-cli/internal/config"
+	"mangadex-cli/internal/db"
+)
+
+// slackMaxSectionText is the Slack limit for a section block's text
+const slackMaxSectionText = 3000
+
+// SlackNotifier posts updates to a Slack incoming webhook using Block Kit
+type SlackNotifier struct {
+	webhookURL string
+}
+
+// NewSlackNotifier creates a Slack incoming webhook notifier
+func NewSlackNotifier(settings config.NotifierConfig) (Notifier, error) {
+	if settings.WebhookURL == "" {
+		return nil, fmt.Errorf("webhook URL must be provided")
+	}
+	return &SlackNotifier{webhookURL: settings.WebhookURL}, nil
+}
+
+// Type returns the backend type
+func (n *SlackNotifier) Type() string {
+	return "slack"
+}
+
+// Notify posts a message with a header, the chapter list and a link button
+func (n *SlackNotifier) Notify(ctx context.Context, user *db.User, manga *api.Manga, chapters []api.Chapter) error {
+	title := fmt.Sprintf("%d new chapter(s) for %s", len(chapters), manga.GetTitle())
+
+	list := chapterList(chapters,
+		func(label string) string {
+			return fmt.Sprintf("*%s*\n", slackEscape(label))
+		},
+		func(chapter api.Chapter) string {
+			return fmt.Sprintf("• <%s|%s> (%s)\n", chapterURL(chapter), slackEscape(chapterLabel(chapter)), slackEscape(chapterSource(chapter)))
+		},
+		slackMaxSectionText)
+
+	section := map[string]interface{}{
+		"type": "section",
+		"text": map[string]string{"type": "mrkdwn", "text": list},
+	}
+	if manga.CoverArtURL != "" {
+		section["accessory"] = map[string]string{
+			"type":      "image",
+			"image_url": manga.CoverArtURL,
+			"alt_text":  manga.GetTitle(),
+		}
+	}
+
+	blocks := []interface{}{
+		map[string]interface{}{
+			"type": "header",
+			"text": map[string]string{"type": "plain_text", "text": truncate(title, 150)},
+		},
+		section,
+		map[string]interface{}{
+			"type": "actions",
+			"elements": []interface{}{
+				map[string]interface{}{
+					"type": "button",
+					"text": map[string]string{"type": "plain_text", "text": "View on MangaDex"},
+					"url":  mangaURL(manga),
+				},
+			},
+		},
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"text":   title, // Fallback for notifications
+		"blocks": blocks,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode Slack payload: %w", err)
+	}
+
+	return postJSON(ctx, n.webhookURL, payload, nil)
+}
+
+// slackEscape escapes the characters Slack treats as markup
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// truncate shortens text to at most max runes
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
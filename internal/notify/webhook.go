@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"mangadex-cli/internal/api"
+	"mangadex-cli/internal/config"
+	"mangadex-cli/internal/db"
+)
+
+// WebhookNotifier posts updates as JSON to any HTTP endpoint. If a secret is
+// configured, the request carries an X-Signature-256 header with the hex
+// HMAC-SHA256 of "<timestamp>.<body>", where timestamp is the X-Timestamp header.
+type WebhookNotifier struct {
+	webhookURL string
+	secret     string
+}
+
+// NewWebhookNotifier creates a generic JSON webhook notifier
+func NewWebhookNotifier(settings config.NotifierConfig) (Notifier, error) {
+	if settings.WebhookURL == "" {
+		return nil, fmt.Errorf("webhook URL must be provided")
+	}
+	return &WebhookNotifier{webhookURL: settings.WebhookURL, secret: settings.Secret}, nil
+}
+
+// Type returns the backend type
+func (n *WebhookNotifier) Type() string {
+	return "webhook"
+}
+
+// webhookPayload is the JSON body sent to generic webhooks
+type webhookPayload struct {
+	Event    string           `json:"event"`
+	SentAt   time.Time        `json:"sent_at"`
+	User     webhookUser      `json:"user"`
+	Manga    webhookManga     `json:"manga"`
+	Chapters []webhookChapter `json:"chapters"`
+}
+
+type webhookUser struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+type webhookManga struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Status   string `json:"status"`
+	URL      string `json:"url"`
+	CoverURL string `json:"cover_url,omitempty"`
+}
+
+type webhookChapter struct {
+	ID        string    `json:"id"`
+	Chapter   string    `json:"chapter"`
+	Volume    string    `json:"volume"`
+	Title     string    `json:"title"`
+	Language  string    `json:"language"`
+	Groups    []string  `json:"groups"`
+	URL       string    `json:"url"`
+	PublishAt time.Time `json:"publish_at"`
+}
+
+// Notify posts the update and signs it if a secret is configured
+func (n *WebhookNotifier) Notify(ctx context.Context, user *db.User, manga *api.Manga, chapters []api.Chapter) error {
+	payload := webhookPayload{
+		Event:  "chapters.new",
+		SentAt: time.Now().UTC(),
+		User:   webhookUser{Email: user.Email, Name: user.Name},
+		Manga: webhookManga{
+			ID:       manga.ID,
+			Title:    manga.GetTitle(),
+			Status:   manga.Status,
+			URL:      mangaURL(manga),
+			CoverURL: manga.CoverArtURL,
+		},
+	}
+	sorted := make([]api.Chapter, len(chapters))
+	copy(sorted, chapters)
+	api.SortChapters(sorted)
+	for _, chapter := range sorted {
+		payload.Chapters = append(payload.Chapters, webhookChapter{
+			ID:        chapter.ID,
+			Chapter:   chapter.Chapter,
+			Volume:    chapter.Volume,
+			Title:     chapter.Title,
+			Language:  chapter.TranslatedLanguage,
+			Groups:    chapter.GetGroupNames(),
+			URL:       chapterURL(chapter),
+			PublishAt: chapter.PublishAt,
+		})
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	headers := map[string]string{}
+	if n.secret != "" {
+		timestamp := strconv.FormatInt(payload.SentAt.Unix(), 10)
+		headers["X-Timestamp"] = timestamp
+		headers["X-Signature-256"] = "sha256=" + sign(n.secret, timestamp, body)
+	}
+
+	return postJSON(ctx, n.webhookURL, body, headers)
+}
+
+// sign computes the hex HMAC-SHA256 of the timestamp and body
+func sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
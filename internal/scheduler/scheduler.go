@@ -1,47 +1,37 @@
 package scheduler
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
 
-	"mangadex-cli/internal/api"
-	"mangadex-cli/internal/db"
-	"mangadex-cli/internal/email"
+	"mangadex-cli/internal/updater"
 
 	"github.com/robfig/cron/v3"
 )
 
+// outboxInterval is how often queued notifications are retried between checks
+const outboxInterval = time.Minute
 
-type UpdateInfo struct {
-	MangaID    string
-	MangaTitle string
-	ChapterIDs []string
-	Chapters   []api.Chapter
-}
 // CronScheduler handles periodic checking for manga updates
 type CronScheduler struct {
-	db           *db.DB
-	apiClient    *api.MangaDexClient
-	emailService *email.EmailService
-	cron         *cron.Cron
-	interval     int // seconds
-	running      bool
+	engine   *updater.Engine
+	cron     *cron.Cron
+	interval int // seconds
+	running  bool
+
+	// ctx is cancelled to abandon the run in progress when stopping
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // NewCronScheduler creates a new scheduler
-func NewCronScheduler(
-	database *db.DB,
-	client *api.MangaDexClient,
-	emailService *email.EmailService,
-	checkInterval int,
-) *CronScheduler {
+func NewCronScheduler(engine *updater.Engine, checkInterval int) *CronScheduler {
 	return &CronScheduler{
-		db:           database,
-		apiClient:    client,
-		emailService: emailService,
-		interval:     checkInterval,
-		running:      false,
+		engine:   engine,
+		interval: checkInterval,
+		running:  false,
 	}
 }
 
@@ -51,21 +41,36 @@ func (s *CronScheduler) Start() error {
 		return fmt.Errorf("scheduler is already running")
 	}
 
-	// Create new cron scheduler
-	s.cron = cron.New(cron.WithSeconds())
+	// Create new cron scheduler. A job still running when it is due again is
+	// skipped rather than queued behind the engine's lock.
+	s.cron = cron.New(cron.WithSeconds(), cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))
+	s.ctx, s.cancel = context.WithCancel(context.Background())
 
 	// Schedule update checks
 	schedule := fmt.Sprintf("@every %ds", s.interval)
 	_, err := s.cron.AddFunc(schedule, func() {
-		if err := s.CheckForUpdates(); err != nil {
+		if err := s.CheckForUpdatesContext(s.ctx); err != nil {
 			log.Printf("Error checking for updates: %v", err)
 		}
 	})
 
 	if err != nil {
+		s.cancel()
 		return fmt.Errorf("failed to schedule update checks: %w", err)
 	}
 
+	// Retry failed notifications without waiting for the next check
+	_, err = s.cron.AddFunc(fmt.Sprintf("@every %s", outboxInterval), func() {
+		if err := s.DeliverOutboxContext(s.ctx); err != nil {
+			log.Printf("Error delivering queued notifications: %v", err)
+		}
+	})
+
+	if err != nil {
+		s.cancel()
+		return fmt.Errorf("failed to schedule outbox delivery: %w", err)
+	}
+
 	// Start the cron scheduler
 	s.cron.Start()
 	s.running = true
@@ -73,141 +78,131 @@ func (s *CronScheduler) Start() error {
 	return nil
 }
 
-// Stop stops the update checking scheduler
+// Stop stops the update checking scheduler, cancelling any run in progress
+// and waiting for it to return
 func (s *CronScheduler) Stop() error {
+	return s.Shutdown(0)
+}
+
+// Shutdown stops scheduling new runs and waits up to timeout for the run in
+// progress to finish. If it is still going after that, it is cancelled and
+// Shutdown waits for it to return; outbox items it had not sent stay queued.
+func (s *CronScheduler) Shutdown(timeout time.Duration) error {
 	if !s.running || s.cron == nil {
 		return nil
 	}
 
-	// Stop the cron scheduler
-	s.cron.Stop()
+	// Stop the cron scheduler; done is closed once running jobs return
+	done := s.cron.Stop().Done()
 	s.running = false
 
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case <-done:
+			s.cancel()
+			return nil
+		case <-timer.C:
+			log.Printf("Update run still in progress after %s, cancelling it", timeout)
+		}
+	}
+
+	s.cancel()
+	<-done
+
 	return nil
 }
 
-// CheckForUpdates checks all active subscriptions for new chapters
+// CheckForUpdates runs the update engine once and logs the outcome
 func (s *CronScheduler) CheckForUpdates() error {
+	return s.CheckForUpdatesContext(context.Background())
+}
+
+// CheckForUpdatesContext is like CheckForUpdates but can be cancelled through
+// the context
+func (s *CronScheduler) CheckForUpdatesContext(ctx context.Context) error {
 	log.Printf("Running scheduled update check at %s", time.Now().Format(time.RFC3339))
 
-	// Get active subscriptions
-	subscriptions, err := s.db.ListActiveSubscriptions()
+	result, err := s.engine.RunContext(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get subscriptions: %w", err)
+		return err
 	}
 
-	if len(subscriptions) == 0 {
+	if ctx.Err() != nil {
+		log.Println("Update check cancelled, unfinished work will be picked up next run")
+	}
+
+	if len(result.Subscriptions) == 0 {
 		log.Println("No active subscriptions found")
 		return nil
 	}
 
-	log.Printf("Checking updates for %d subscriptions...", len(subscriptions))
-
-	// Track new chapters by user
-
-	updates := make(map[int]map[string]*UpdateInfo) // UserID -> MangaID -> UpdateInfo
-
-	// Check each subscription for updates
-	for _, sub := range subscriptions {
-		log.Printf("Checking \"%s\"...", sub.MangaTitle)
-
-		// Get new chapters since last check
-		chapters, err := s.apiClient.GetMangaChapters(sub.MangaID, sub.LastCheckTime)
-		if err != nil {
-			log.Printf("Error checking \"%s\": %v", sub.MangaTitle, err)
-			continue
-		}
-
-		// Filter chapters by language
-		languages := sub.GetLanguages()
-		filteredChapters := make([]api.Chapter, 0)
-
-		for _, chapter := range chapters {
-			for _, lang := range languages {
-				if chapter.TranslatedLanguage == lang {
-					filteredChapters = append(filteredChapters, chapter)
-					break
-				}
-			}
-		}
-
-		// Update last check time
-		sub.LastCheckTime = time.Now()
-		if err := s.db.UpdateSubscription(&sub); err != nil {
-			log.Printf("Error updating subscription check time: %v", err)
-		}
+	for _, err := range result.Errors {
+		log.Printf("Warning: %v", err)
+	}
 
-		// If no new chapters, continue
-		if len(filteredChapters) == 0 {
-			log.Printf("No new chapters for \"%s\"", sub.MangaTitle)
-			continue
+	for _, sub := range result.Subscriptions {
+		switch {
+		case sub.Err != nil:
+			log.Printf("Error checking \"%s\": %v", sub.Subscription.MangaTitle, sub.Err)
+		case len(sub.Chapters) == 0:
+			log.Printf("No new chapters for \"%s\"", sub.Subscription.MangaTitle)
+		default:
+			log.Printf("Found %d new chapter(s) for \"%s\"", len(sub.Chapters), sub.Subscription.MangaTitle)
 		}
-
-		// Group updates by user and manga
-		if _, ok := updates[sub.UserID]; !ok {
-			updates[sub.UserID] = make(map[string]*UpdateInfo)
+		if sub.Truncated {
+			log.Printf("Chapter limit reached for \"%s\", remaining chapters will be fetched next run", sub.Subscription.MangaTitle)
 		}
-
-		if _, ok := updates[sub.UserID][sub.MangaID]; !ok {
-			updates[sub.UserID][sub.MangaID] = &UpdateInfo{
-				MangaID:    sub.MangaID,
-				MangaTitle: sub.MangaTitle,
-				ChapterIDs: make([]string, 0),
-				Chapters:   make([]api.Chapter, 0),
-			}
+		if sub.Held > 0 {
+			log.Printf("Waiting for other releases of %d chapter(s) of \"%s\"", sub.Held, sub.Subscription.MangaTitle)
 		}
+	}
 
-		// Add chapters to user updates
-		updateInfo := updates[sub.UserID][sub.MangaID]
-		for _, chapter := range filteredChapters {
-			updateInfo.ChapterIDs = append(updateInfo.ChapterIDs, chapter.ID)
-			updateInfo.Chapters = append(updateInfo.Chapters, chapter)
-		}
+	logNotifications(result)
 
-		log.Printf("Found %d new chapter(s) for \"%s\"", len(filteredChapters), sub.MangaTitle)
+	for _, d := range result.Deferred {
+		log.Printf("Holding %d chapter(s) for %s until their digest at %s",
+			d.Chapters, d.Email, d.DueAt.Format(time.RFC3339))
 	}
 
-	// Process notifications for each user
-	for userID, mangaUpdates := range updates {
-		if err := s.ProcessUserNotifications(userID, mangaUpdates); err != nil {
-			log.Printf("Error processing notifications for user %d: %v", userID, err)
-		}
-	}
+	log.Printf("Checked %d subscriptions with %d API call(s), %d chapter fetch(es) saved by sharing: %d new chapter(s), %d notification(s) queued, %d sent, %d error(s)",
+		len(result.Subscriptions), result.APICalls, result.APICallsSaved, result.ChaptersFound(), result.Queued, result.NotificationsSent(), result.ErrorCount())
 
 	return nil
 }
 
-// ProcessUserNotifications sends notifications for a specific user's manga updates
-func (s *CronScheduler) ProcessUserNotifications(userID int, mangaUpdates map[string]*UpdateInfo) error {
-	// Get user
-	user, err := s.db.GetUser(userID)
+// DeliverOutbox retries due outbox items between update checks
+func (s *CronScheduler) DeliverOutbox() error {
+	return s.DeliverOutboxContext(context.Background())
+}
+
+// DeliverOutboxContext is like DeliverOutbox but can be cancelled through the
+// context
+func (s *CronScheduler) DeliverOutboxContext(ctx context.Context) error {
+	result, err := s.engine.DeliverOutboxContext(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get user with ID %d: %w", userID, err)
+		return err
 	}
 
-	// Connect to email server
-	if err := s.emailService.Connect(); err != nil {
-		return fmt.Errorf("failed to connect to email server: %w", err)
-	}
-	defer s.emailService.Disconnect()
-
-	// Send notification for each manga with updates
-	for _, updateInfo := range mangaUpdates {
-		// Get manga details
-		manga, err := s.apiClient.GetManga(updateInfo.MangaID)
-		if err != nil {
-			log.Printf("Error getting manga details for \"%s\": %v", updateInfo.MangaTitle, err)
-			continue
-		}
+	logNotifications(result)
+	return nil
+}
 
-		// Send notification
-		if err := s.emailService.SendNotification(user.Email, manga, updateInfo.Chapters); err != nil {
-			log.Printf("Error sending notification to %s: %v", user.Email, err)
-		} else {
-			log.Printf("Notification sent to %s about %d new chapter(s) for \"%s\"",
-				user.Email, len(updateInfo.Chapters), updateInfo.MangaTitle)
+// logNotifications logs the outcome of every delivery attempt
+func logNotifications(result *updater.Result) {
+	for _, n := range result.Notifications {
+		switch {
+		case n.Interrupted:
+			log.Printf("Notifying user %d via %s about \"%s\" was interrupted, will retry: %v", n.UserID, n.Channel, n.MangaTitle, n.Err)
+		case n.Err != nil && n.RetryAt.IsZero():
+			log.Printf("Giving up notifying user %d via %s about \"%s\" after %d attempt(s): %v", n.UserID, n.Channel, n.MangaTitle, n.Attempt, n.Err)
+		case n.Err != nil:
+			log.Printf("Error notifying user %d via %s about \"%s\", retrying at %s: %v", n.UserID, n.Channel, n.MangaTitle, n.RetryAt.Format(time.RFC3339), n.Err)
+		default:
+			log.Printf("Notification sent to %s via %s about %d new chapter(s) for \"%s\"",
+				n.Email, n.Channel, len(n.Chapters), n.MangaTitle)
 		}
 	}
-
-	return nil
 }
@@ -0,0 +1,159 @@
+package unsubscribe
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+
+	"mangadex-cli/internal/db"
+)
+
+// pageTemplate is the confirmation and result page
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+	<head>
+		<meta charset="UTF-8">
+		<meta name="viewport" content="width=device-width, initial-scale=1.0">
+		<title>{{.Title}}</title>
+		<style>
+			body { font-family: Arial, sans-serif; color: #333; max-width: 480px; margin: 40px auto; padding: 0 20px; }
+			h1 { color: #FF6740; font-size: 22px; }
+			button { background-color: #FF6740; color: white; border: 0; padding: 10px 15px; border-radius: 4px; font-size: 15px; cursor: pointer; }
+		</style>
+	</head>
+	<body>
+		<h1>{{.Title}}</h1>
+		<p>{{.Message}}</p>
+		{{if .Confirm}}<form method="post"><button type="submit">Unsubscribe</button></form>{{end}}
+	</body>
+</html>
+`))
+
+// page is the data for pageTemplate
+type page struct {
+	Title   string
+	Message string
+	Confirm bool
+}
+
+// Handler serves unsubscribe links. GET shows a confirmation page, so link
+// scanners that follow every URL in an email do not unsubscribe anyone; POST
+// unsubscribes, both from that page and from one-click requests sent by mail
+// clients as described in RFC 8058.
+type Handler struct {
+	db     *db.DB
+	signer *Signer
+}
+
+// NewHandler creates the unsubscribe handler
+func NewHandler(database *db.DB, signer *Signer) *Handler {
+	return &Handler{db: database, signer: signer}
+}
+
+// ServeHTTP handles unsubscribe requests
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, err := h.signer.Verify(r.URL.Query().Get("token"))
+	if errors.Is(err, ErrExpired) {
+		h.render(w, http.StatusBadRequest, page{
+			Title:   "Expired link",
+			Message: "This unsubscribe link has expired. Please use the link from the most recent email.",
+		})
+		return
+	}
+	if err != nil {
+		h.render(w, http.StatusBadRequest, page{
+			Title:   "Invalid link",
+			Message: "This unsubscribe link is invalid. Please use the link from the most recent email.",
+		})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		message := "Stop all MangaDex update emails to this address?"
+		if token.Scope == ScopeManga {
+			message = fmt.Sprintf("Stop update emails for %s?", h.mangaTitle(token))
+		}
+		h.render(w, http.StatusOK, page{Title: "Unsubscribe", Message: message, Confirm: true})
+
+	case http.MethodPost:
+		message, err := h.apply(token)
+		if err != nil {
+			log.Printf("Error unsubscribing user %d: %v", token.UserID, err)
+			h.render(w, http.StatusInternalServerError, page{
+				Title:   "Something went wrong",
+				Message: "You could not be unsubscribed. Please try again later.",
+			})
+			return
+		}
+		log.Printf("User %d unsubscribed: %s", token.UserID, message)
+		h.render(w, http.StatusOK, page{Title: "Unsubscribed", Message: message})
+
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// apply deactivates the user or their subscriptions to the manga
+func (h *Handler) apply(token Token) (string, error) {
+	user, err := h.db.GetUser(token.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	if token.Scope == ScopeUser {
+		user.Active = false
+		if err := h.db.UpdateUser(user); err != nil {
+			return "", err
+		}
+		return "You will no longer receive MangaDex update emails.", nil
+	}
+
+	subscriptions, err := h.db.GetSubscriptionsByUserID(user.ID)
+	if err != nil {
+		return "", err
+	}
+
+	title := token.MangaID
+	for i := range subscriptions {
+		sub := &subscriptions[i]
+		if sub.MangaID != token.MangaID {
+			continue
+		}
+		title = sub.MangaTitle
+		if !sub.Active {
+			continue
+		}
+		sub.Active = false
+		if err := h.db.UpdateSubscription(sub); err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("You will no longer receive update emails for %s.", title), nil
+}
+
+// mangaTitle returns the title of a subscribed manga for the confirmation page
+func (h *Handler) mangaTitle(token Token) string {
+	subscriptions, err := h.db.GetSubscriptionsByUserID(token.UserID)
+	if err == nil {
+		for _, sub := range subscriptions {
+			if sub.MangaID == token.MangaID {
+				return sub.MangaTitle
+			}
+		}
+	}
+	return "this manga"
+}
+
+// render writes a page
+func (h *Handler) render(w http.ResponseWriter, status int, data page) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := pageTemplate.Execute(w, data); err != nil {
+		log.Printf("Error rendering unsubscribe page: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package unsubscribe
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Token scopes
+const (
+	ScopeUser  = "u" // Every notification to a user
+	ScopeManga = "m" // A user's subscriptions to one manga
+)
+
+// DefaultTokenTTL is how long unsubscribe links stay valid
+const DefaultTokenTTL = 365 * 24 * time.Hour
+
+// ErrExpired is returned for a correctly signed token past its expiry
+var ErrExpired = errors.New("unsubscribe link has expired")
+
+// Token identifies what a recipient unsubscribes from
+type Token struct {
+	Scope     string
+	UserID    int
+	MangaID   string    // Only for ScopeManga
+	ExpiresAt time.Time // Zero if the token does not expire
+}
+
+// Signer creates and verifies unsubscribe tokens, which are signed with
+// HMAC-SHA256 so recipients cannot unsubscribe anyone else
+type Signer struct {
+	TTL time.Duration // How long signed tokens stay valid; zero for no expiry
+
+	secret  []byte
+	baseURL string
+	now     func() time.Time
+}
+
+// NewSigner creates a signer for links to the unsubscribe handler at baseURL
+func NewSigner(secret, baseURL string) *Signer {
+	return &Signer{TTL: DefaultTokenTTL, secret: []byte(secret), baseURL: baseURL, now: time.Now}
+}
+
+// GenerateSecret returns a random signing secret
+func GenerateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate unsubscribe secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// Sign encodes a token as "payload.signature", expiring after the signer's
+// TTL unless the token sets its own expiry
+func (s *Signer) Sign(token Token) string {
+	if token.ExpiresAt.IsZero() && s.TTL > 0 {
+		token.ExpiresAt = s.now().Add(s.TTL)
+	}
+	var expires int64
+	if !token.ExpiresAt.IsZero() {
+		expires = token.ExpiresAt.Unix()
+	}
+
+	payload := token.Scope + ":" + strconv.Itoa(token.UserID) + ":" + strconv.FormatInt(expires, 10)
+	if token.Scope == ScopeManga {
+		payload += ":" + token.MangaID
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.mac(encoded))
+}
+
+// Verify checks a token's signature and expiry and decodes it. Expired
+// tokens return ErrExpired.
+func (s *Signer) Verify(signed string) (Token, error) {
+	parts := strings.Split(signed, ".")
+	if len(parts) != 2 {
+		return Token{}, fmt.Errorf("malformed token")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, s.mac(parts[0])) {
+		return Token{}, fmt.Errorf("invalid token signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return Token{}, fmt.Errorf("malformed token")
+	}
+
+	fields := strings.SplitN(string(payload), ":", 4)
+	if len(fields) < 3 {
+		return Token{}, fmt.Errorf("malformed token")
+	}
+	userID, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return Token{}, fmt.Errorf("malformed token")
+	}
+	expires, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return Token{}, fmt.Errorf("malformed token")
+	}
+
+	token := Token{Scope: fields[0], UserID: userID}
+	switch {
+	case token.Scope == ScopeUser && len(fields) == 3:
+	case token.Scope == ScopeManga && len(fields) == 4 && fields[3] != "":
+		token.MangaID = fields[3]
+	default:
+		return Token{}, fmt.Errorf("malformed token")
+	}
+
+	if expires != 0 {
+		token.ExpiresAt = time.Unix(expires, 0)
+		if !s.now().Before(token.ExpiresAt) {
+			return Token{}, ErrExpired
+		}
+	}
+	return token, nil
+}
+
+// mac signs an encoded payload
+func (s *Signer) mac(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// UserURL returns the link that stops every notification to a user
+func (s *Signer) UserURL(userID int) string {
+	return s.link(Token{Scope: ScopeUser, UserID: userID})
+}
+
+// MangaURL returns the link that stops a user's notifications for one manga
+func (s *Signer) MangaURL(userID int, mangaID string) string {
+	return s.link(Token{Scope: ScopeManga, UserID: userID, MangaID: mangaID})
+}
+
+// link builds the handler URL for a token
+func (s *Signer) link(token Token) string {
+	separator := "?"
+	if strings.Contains(s.baseURL, "?") {
+		separator = "&"
+	}
+	return s.baseURL + separator + "token=" + url.QueryEscape(s.Sign(token))
+}
@@ -0,0 +1,224 @@
+package unsubscribe
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"mangadex-cli/internal/db"
+)
+
+// testSigner returns a signer whose clock is fixed at now
+func testSigner(secret string, now time.Time) *Signer {
+	s := NewSigner(secret, "https://example.com/unsubscribe")
+	s.now = func() time.Time { return now }
+	return s
+}
+
+// resign replaces a token's payload and signs it with another secret
+func resign(payload, secret string) string {
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	s := NewSigner(secret, "")
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.mac(encoded))
+}
+
+func TestSignVerify(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	signer := testSigner("secret", now)
+
+	tests := []Token{
+		{Scope: ScopeUser, UserID: 1},
+		{Scope: ScopeManga, UserID: 42, MangaID: "a1c7c817-4e59-43b7-9365-09675a149a6f"},
+	}
+	for _, token := range tests {
+		got, err := signer.Verify(signer.Sign(token))
+		if err != nil {
+			t.Fatalf("Verify(Sign(%+v)) failed: %v", token, err)
+		}
+		if got.Scope != token.Scope || got.UserID != token.UserID || got.MangaID != token.MangaID {
+			t.Errorf("Verify(Sign(%+v)) = %+v", token, got)
+		}
+		if want := now.Add(DefaultTokenTTL); !got.ExpiresAt.Equal(want) {
+			t.Errorf("token expires at %s, want %s", got.ExpiresAt, want)
+		}
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	signer := testSigner("secret", now)
+	valid := signer.Sign(Token{Scope: ScopeManga, UserID: 1, MangaID: "manga"})
+	parts := strings.Split(valid, ".")
+	expires := now.Add(time.Hour).Unix()
+
+	// Payloads are "scope:user:expires[:manga]"
+	forge := func(payload string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + parts[1]
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		expired bool
+	}{
+		{name: "empty", token: ""},
+		{name: "no signature", token: parts[0]},
+		{name: "extra part", token: valid + ".x"},
+		{name: "tampered signature", token: parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte("not the signature"))},
+		{name: "undecodable signature", token: parts[0] + ".!!"},
+		{name: "other user", token: forge("m:2:" + formatUnix(expires) + ":manga")},
+		{name: "other manga", token: forge("m:1:" + formatUnix(expires) + ":other")},
+		{name: "widened scope", token: forge("u:1:" + formatUnix(expires))},
+		{name: "extended expiry", token: forge("m:1:0:manga")},
+		{name: "other secret", token: resign("u:1:"+formatUnix(expires), "other secret")},
+		{name: "unknown scope", token: resign("x:1:"+formatUnix(expires), "secret")},
+		{name: "user scope with manga", token: resign("u:1:"+formatUnix(expires)+":manga", "secret")},
+		{name: "manga scope without manga", token: resign("m:1:"+formatUnix(expires), "secret")},
+		{name: "no expiry field", token: resign("u:1", "secret")},
+		{name: "bad user", token: resign("u:one:"+formatUnix(expires), "secret")},
+		{name: "expired", token: resign("u:1:"+formatUnix(now.Add(-time.Second).Unix()), "secret"), expired: true},
+		{name: "expires now", token: resign("u:1:"+formatUnix(now.Unix()), "secret"), expired: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := signer.Verify(tt.token)
+			if err == nil {
+				t.Fatalf("Verify accepted %q as %+v", tt.token, token)
+			}
+			if errors.Is(err, ErrExpired) != tt.expired {
+				t.Errorf("Verify error = %v, expired %t", err, tt.expired)
+			}
+		})
+	}
+}
+
+func TestVerifyExpiry(t *testing.T) {
+	issued := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	signed := testSigner("secret", issued).Sign(Token{Scope: ScopeUser, UserID: 1})
+
+	tests := []struct {
+		at      time.Time
+		expired bool
+	}{
+		{issued, false},
+		{issued.Add(DefaultTokenTTL - time.Second), false},
+		{issued.Add(DefaultTokenTTL), true},
+		{issued.Add(DefaultTokenTTL + time.Hour), true},
+	}
+	for _, tt := range tests {
+		_, err := testSigner("secret", tt.at).Verify(signed)
+		if expired := errors.Is(err, ErrExpired); expired != tt.expired || (err != nil && !expired) {
+			t.Errorf("Verify at %s: error %v, want expired %t", tt.at, err, tt.expired)
+		}
+	}
+
+	// Without a TTL, tokens do not expire
+	forever := testSigner("secret", issued)
+	forever.TTL = 0
+	signed = forever.Sign(Token{Scope: ScopeUser, UserID: 1})
+	if _, err := testSigner("secret", issued.Add(100*DefaultTokenTTL)).Verify(signed); err != nil {
+		t.Errorf("token without expiry rejected: %v", err)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		prefix  string
+	}{
+		{"https://example.com/unsubscribe", "https://example.com/unsubscribe?token="},
+		{"https://example.com/u?list=manga", "https://example.com/u?list=manga&token="},
+	}
+	for _, tt := range tests {
+		signer := NewSigner("secret", tt.baseURL)
+		link := signer.MangaURL(7, "manga")
+		if !strings.HasPrefix(link, tt.prefix) {
+			t.Fatalf("MangaURL = %q, want prefix %q", link, tt.prefix)
+		}
+		parsed, err := url.Parse(link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := signer.Verify(parsed.Query().Get("token"))
+		if err != nil || token.UserID != 7 || token.MangaID != "manga" {
+			t.Errorf("link token = %+v, %v", token, err)
+		}
+	}
+}
+
+func TestHandlerUnsubscribesOnlyTokenUser(t *testing.T) {
+	database, err := db.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := []*db.User{{Email: "one@example.com", Active: true}, {Email: "two@example.com", Active: true}}
+	subs := make([]*db.Subscription, 0)
+	for _, user := range users {
+		if err := database.AddUser(user); err != nil {
+			t.Fatal(err)
+		}
+		for _, mangaID := range []string{"manga", "other"} {
+			sub := &db.Subscription{UserID: user.ID, MangaID: mangaID, Active: true}
+			if err := database.AddSubscription(sub); err != nil {
+				t.Fatal(err)
+			}
+			subs = append(subs, sub)
+		}
+	}
+
+	signer := NewSigner("secret", "https://example.com/unsubscribe")
+	handler := NewHandler(database, signer)
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		status int
+	}{
+		{"confirmation page does not unsubscribe", http.MethodGet, signer.Sign(Token{Scope: ScopeManga, UserID: users[0].ID, MangaID: "manga"}), http.StatusOK},
+		{"forged token", http.MethodPost, resign("m:2:0:manga", "wrong secret"), http.StatusBadRequest},
+		{"one manga", http.MethodPost, signer.Sign(Token{Scope: ScopeManga, UserID: users[0].ID, MangaID: "manga"}), http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/unsubscribe?token="+url.QueryEscape(tt.token), nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.status)
+		}
+	}
+
+	// Only user one's subscription to the manga ends
+	want := []bool{false, true, true, true}
+	for i, sub := range subs {
+		got, err := database.GetSubscription(sub.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Active != want[i] {
+			t.Errorf("subscription of user %d to %s active = %t, want %t", got.UserID, got.MangaID, got.Active, want[i])
+		}
+	}
+	for _, user := range users {
+		got, err := database.GetUser(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Active {
+			t.Errorf("user %s was unsubscribed", got.Email)
+		}
+	}
+}
+
+// formatUnix formats a Unix time for a token payload
+func formatUnix(sec int64) string {
+	return strconv.FormatInt(sec, 10)
+}
@@ -0,0 +1,133 @@
+package updater
+
+import (
+	"time"
+
+	"mangadex-cli/internal/db"
+)
+
+// defaultDedupWindow is how long the wait policy waits for other releases
+// of a chapter when the subscription does not set a window
+const defaultDedupWindow = 60 * time.Minute
+
+// dedupReleases applies a subscription's duplicate release policy to its
+// pending chapters. Releases of a chapter that will never be delivered are
+// marked superseded in the ledger. It returns the chapters to notify now and
+// the number held back until the wait window of their chapter has passed.
+func (e *Engine) dedupReleases(sub db.Subscription, pending []db.SeenChapter, now time.Time) ([]db.SeenChapter, int, error) {
+	policy := sub.GetDedupPolicy()
+	if policy == db.DedupAll || len(pending) == 0 {
+		return pending, 0, nil
+	}
+
+	keys := make([]string, 0)
+	seenKeys := make(map[string]bool)
+	for _, p := range pending {
+		if p.ReleaseKey != "" && !seenKeys[p.ReleaseKey] {
+			seenKeys[p.ReleaseKey] = true
+			keys = append(keys, p.ReleaseKey)
+		}
+	}
+
+	releases, err := e.db.ListReleases(sub.ID, keys)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Group every known release, oldest first
+	groups := make(map[string][]db.SeenChapter)
+	for _, release := range releases {
+		key := dedupKey(release, policy)
+		groups[key] = append(groups[key], release)
+	}
+
+	window := time.Duration(sub.DedupWindow) * time.Minute
+	if window <= 0 {
+		window = defaultDedupWindow
+	}
+
+	ready := make(map[string]bool)
+	held := 0
+	superseded := make([]string, 0)
+	decided := make(map[string]bool)
+
+	for _, p := range pending {
+		if p.ReleaseKey == "" {
+			ready[p.ChapterID] = true
+			continue
+		}
+
+		key := dedupKey(p, policy)
+		if decided[key] {
+			continue
+		}
+		decided[key] = true
+
+		group := groups[key]
+		candidates := make([]db.SeenChapter, 0, len(group))
+		claimed := false
+		for _, release := range group {
+			switch {
+			case release.Notified && !release.Superseded:
+				claimed = true
+			case !release.Notified && release.DeliveredChannels != "":
+				// Partly delivered; finish delivering it
+				claimed = true
+				ready[release.ChapterID] = true
+			case !release.Notified:
+				candidates = append(candidates, release)
+			}
+		}
+
+		if !claimed {
+			if policy == db.DedupWait && now.Before(group[0].ChapterCreatedAt.Add(window)) {
+				held += len(candidates)
+				continue
+			}
+
+			pick := candidates[0]
+			if policy == db.DedupWait {
+				pick = preferredRelease(candidates, sub.GetPreferredGroups())
+			}
+			ready[pick.ChapterID] = true
+		}
+
+		for _, candidate := range candidates {
+			if !ready[candidate.ChapterID] {
+				superseded = append(superseded, candidate.ChapterID)
+			}
+		}
+	}
+
+	if err := e.db.SupersedeChapters(sub.ID, superseded); err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]db.SeenChapter, 0, len(ready))
+	for _, p := range pending {
+		if ready[p.ChapterID] {
+			result = append(result, p)
+		}
+	}
+
+	return result, held, nil
+}
+
+// dedupKey identifies the releases a policy treats as duplicates
+func dedupKey(chapter db.SeenChapter, policy string) string {
+	if policy == db.DedupGroup {
+		return chapter.ReleaseKey + "|" + chapter.Groups
+	}
+	return chapter.ReleaseKey
+}
+
+// preferredRelease returns the oldest release by a preferred group, or the
+// oldest release if no preferred group released the chapter
+func preferredRelease(releases []db.SeenChapter, preferred []string) db.SeenChapter {
+	for _, release := range releases {
+		if hasGroup(toAPIChapter(release), preferred) {
+			return release
+		}
+	}
+	return releases[0]
+}
@@ -0,0 +1,282 @@
+package updater
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"mangadex-cli/internal/api"
+	"mangadex-cli/internal/db"
+)
+
+var dedupStart = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// release is a ledger entry for a test chapter uploaded minutes after dedupStart
+type release struct {
+	id        string
+	language  string
+	chapter   string
+	group     string
+	minutes   int
+	notified  bool
+	delivered string // Channels the release was delivered on
+}
+
+func (r release) seen() db.SeenChapter {
+	language := r.language
+	if language == "" {
+		language = "en"
+	}
+	seen := toSeenChapter("manga", api.Chapter{
+		ID:                 r.id,
+		Chapter:            r.chapter,
+		TranslatedLanguage: language,
+		Groups:             []string{r.group},
+		GroupNames:         []string{"Group " + r.group},
+		CreatedAt:          dedupStart.Add(time.Duration(r.minutes) * time.Minute),
+	})
+	seen.Notified = r.notified
+	seen.DeliveredChannels = r.delivered
+	return seen
+}
+
+func TestDedupReleases(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     string
+		window     int    // Subscription dedup window in minutes
+		preferred  string // Subscription preferred groups
+		releases   []release
+		at         int // Minutes after dedupStart the policy is applied
+		ready      []string
+		held       int
+		superseded []string
+	}{
+		{
+			name:   "all notifies every release",
+			policy: db.DedupAll,
+			releases: []release{
+				{id: "a", chapter: "10", group: "g1"},
+				{id: "b", chapter: "10", group: "g2", minutes: 5},
+			},
+			ready: []string{"a", "b"},
+		},
+		{
+			name:   "first notifies the oldest release",
+			policy: db.DedupFirst,
+			releases: []release{
+				{id: "a", chapter: "10", group: "g1"},
+				{id: "b", chapter: "10", group: "g2", minutes: 5},
+				{id: "c", chapter: "11", group: "g2", minutes: 5},
+			},
+			ready:      []string{"a", "c"},
+			superseded: []string{"b"},
+		},
+		{
+			name:   "first skips releases of a notified chapter",
+			policy: db.DedupFirst,
+			releases: []release{
+				{id: "a", chapter: "10", group: "g1", notified: true},
+				{id: "b", chapter: "10", group: "g2", minutes: 5},
+			},
+			superseded: []string{"b"},
+		},
+		{
+			name:   "first finishes a partly delivered release",
+			policy: db.DedupFirst,
+			releases: []release{
+				{id: "a", chapter: "10", group: "g1"},
+				{id: "b", chapter: "10", group: "g2", minutes: 5, delivered: "email"},
+			},
+			ready:      []string{"b"},
+			superseded: []string{"a"},
+		},
+		{
+			name:   "first keeps languages apart",
+			policy: db.DedupFirst,
+			releases: []release{
+				{id: "a", chapter: "10", group: "g1"},
+				{id: "b", language: "fr", chapter: "10", group: "g2", minutes: 5},
+			},
+			ready: []string{"a", "b"},
+		},
+		{
+			name:   "first never matches oneshots",
+			policy: db.DedupFirst,
+			releases: []release{
+				{id: "a", group: "g1"},
+				{id: "b", group: "g2", minutes: 5},
+			},
+			ready: []string{"a", "b"},
+		},
+		{
+			name:   "group notifies the first release of each group",
+			policy: db.DedupGroup,
+			releases: []release{
+				{id: "a", chapter: "10", group: "g1"},
+				{id: "b", chapter: "10", group: "g2", minutes: 5},
+				{id: "c", chapter: "10", group: "g1", minutes: 10},
+			},
+			ready:      []string{"a", "b"},
+			superseded: []string{"c"},
+		},
+		{
+			name:   "wait holds releases inside the window",
+			policy: db.DedupWait,
+			window: 30,
+			releases: []release{
+				{id: "a", chapter: "10", group: "g1"},
+				{id: "b", chapter: "10", group: "g2", minutes: 5},
+			},
+			at:   29,
+			held: 2,
+		},
+		{
+			name:      "wait picks the preferred group when the window ends",
+			policy:    db.DedupWait,
+			window:    30,
+			preferred: "Group g2",
+			releases: []release{
+				{id: "a", chapter: "10", group: "g1"},
+				{id: "b", chapter: "10", group: "g2", minutes: 5},
+			},
+			at:         30,
+			ready:      []string{"b"},
+			superseded: []string{"a"},
+		},
+		{
+			name:      "wait window starts at the first release",
+			policy:    db.DedupWait,
+			window:    30,
+			preferred: "g2",
+			releases: []release{
+				{id: "a", chapter: "10", group: "g1"},
+				{id: "b", chapter: "10", group: "g2", minutes: 29},
+			},
+			at:         30,
+			ready:      []string{"b"},
+			superseded: []string{"a"},
+		},
+		{
+			name:   "wait falls back to the oldest release",
+			policy: db.DedupWait,
+			window: 30,
+			releases: []release{
+				{id: "a", chapter: "10", group: "g1"},
+				{id: "b", chapter: "10", group: "g2", minutes: 5},
+			},
+			at:         31,
+			ready:      []string{"a"},
+			superseded: []string{"b"},
+		},
+		{
+			name:   "wait defaults to an hour",
+			policy: db.DedupWait,
+			releases: []release{
+				{id: "a", chapter: "10", group: "g1"},
+				{id: "b", chapter: "11", group: "g1", minutes: 1},
+			},
+			at:    60,
+			ready: []string{"a"},
+			held:  1,
+		},
+		{
+			name:   "wait skips releases of a notified chapter",
+			policy: db.DedupWait,
+			window: 30,
+			releases: []release{
+				{id: "a", chapter: "10", group: "g1", notified: true},
+				{id: "b", chapter: "10", group: "g2", minutes: 5},
+			},
+			at:         5,
+			superseded: []string{"b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, err := db.NewDB(filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			user := &db.User{Email: "reader@example.com"}
+			if err := database.AddUser(user); err != nil {
+				t.Fatal(err)
+			}
+			sub := &db.Subscription{
+				UserID:          user.ID,
+				MangaID:         "manga",
+				DedupPolicy:     tt.policy,
+				DedupWindow:     tt.window,
+				PreferredGroups: tt.preferred,
+			}
+			if err := database.AddSubscription(sub); err != nil {
+				t.Fatal(err)
+			}
+
+			seen := make([]db.SeenChapter, 0, len(tt.releases))
+			for _, r := range tt.releases {
+				seen = append(seen, r.seen())
+			}
+			if _, err := database.RecordSeenChapters(sub, seen); err != nil {
+				t.Fatal(err)
+			}
+			pending, err := database.ListPendingChapters(sub.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			engine := NewEngine(database, nil, nil)
+			now := dedupStart.Add(time.Duration(tt.at) * time.Minute)
+			ready, held, err := engine.dedupReleases(*sub, pending, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := chapterIDs(ready, func(db.SeenChapter) bool { return true }); !reflect.DeepEqual(got, sorted(tt.ready)) {
+				t.Errorf("ready = %v, want %v", got, tt.ready)
+			}
+			if held != tt.held {
+				t.Errorf("held = %d, want %d", held, tt.held)
+			}
+
+			after, err := database.ListReleases(sub.ID, releaseKeys(seen))
+			if err != nil {
+				t.Fatal(err)
+			}
+			superseded := chapterIDs(after, func(c db.SeenChapter) bool { return c.Superseded })
+			if !reflect.DeepEqual(superseded, sorted(tt.superseded)) {
+				t.Errorf("superseded = %v, want %v", superseded, tt.superseded)
+			}
+		})
+	}
+}
+
+// chapterIDs returns the sorted IDs of the chapters that match keep
+func chapterIDs(chapters []db.SeenChapter, keep func(db.SeenChapter) bool) []string {
+	ids := make([]string, 0)
+	for _, chapter := range chapters {
+		if keep(chapter) {
+			ids = append(ids, chapter.ChapterID)
+		}
+	}
+	return sorted(ids)
+}
+
+// releaseKeys returns the release keys of chapters
+func releaseKeys(chapters []db.SeenChapter) []string {
+	keys := make([]string, 0, len(chapters))
+	for _, chapter := range chapters {
+		keys = append(keys, chapter.ReleaseKey)
+	}
+	return keys
+}
+
+// sorted returns a sorted copy of ids, never nil
+func sorted(ids []string) []string {
+	out := append([]string{}, ids...)
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,75 @@
+package updater
+
+import (
+	"strings"
+	"time"
+
+	"mangadex-cli/internal/db"
+)
+
+// defaultDigestTime is used when a daily or weekly digest has no time of day
+const defaultDigestTime = "08:00"
+
+// nextDigest returns when a user's next digest is due. A time at or before
+// now means a digest is due on this run.
+func nextDigest(user *db.User, now time.Time) time.Time {
+	last := user.LastDigestAt
+	if last.IsZero() {
+		return now
+	}
+
+	switch user.GetDeliveryMode() {
+	case db.DeliveryHourly:
+		return last.Add(time.Hour)
+	case db.DeliveryDaily:
+		return nextSlot(last, user.DeliveryTime, -1)
+	case db.DeliveryWeekly:
+		weekday, ok := ParseWeekday(user.DeliveryWeekday)
+		if !ok {
+			weekday = time.Monday
+		}
+		return nextSlot(last, user.DeliveryTime, weekday)
+	default:
+		return now
+	}
+}
+
+// nextSlot returns the first digest slot after last, at the given time of day
+// and, unless weekday is negative, on the given weekday
+func nextSlot(last time.Time, clock string, weekday time.Weekday) time.Time {
+	hour, minute, ok := ParseClock(clock)
+	if !ok {
+		hour, minute, _ = ParseClock(defaultDigestTime)
+	}
+
+	local := last.Local()
+	slot := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, time.Local)
+	for !slot.After(last) || (weekday >= 0 && slot.Weekday() != weekday) {
+		slot = slot.AddDate(0, 0, 1)
+	}
+	return slot
+}
+
+// ParseClock parses an HH:MM time of day
+func ParseClock(value string) (hour, minute int, ok bool) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, 0, false
+	}
+	return t.Hour(), t.Minute(), true
+}
+
+// ParseWeekday parses an English weekday name such as "monday" or "mon"
+func ParseWeekday(value string) (time.Weekday, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if len(value) < 3 {
+		return 0, false
+	}
+
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.HasPrefix(strings.ToLower(day.String()), value) {
+			return day, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,92 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"mangadex-cli/internal/api"
+	"mangadex-cli/internal/db"
+)
+
+// splitByFollows separates subscriptions to manga the logged-in account
+// follows, which can be served by the follow feed, from the rest
+func (e *Engine) splitByFollows(ctx context.Context, subscriptions []db.Subscription) (followed, unfollowed []db.Subscription, err error) {
+	mangas, err := e.apiClient.GetFollowedMangaContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	follows := make(map[string]bool, len(mangas))
+	for _, manga := range mangas {
+		follows[manga.ID] = true
+	}
+
+	for _, sub := range subscriptions {
+		if follows[sub.MangaID] {
+			followed = append(followed, sub)
+		} else {
+			unfollowed = append(unfollowed, sub)
+		}
+	}
+
+	return followed, unfollowed, nil
+}
+
+// checkFeed reads the follow feed once, from the oldest cursor and across the
+// languages and content ratings of all given subscriptions, and fans the
+// chapters out to them
+func (e *Engine) checkFeed(ctx context.Context, subscriptions []db.Subscription) ([]SubscriptionResult, error) {
+	since := cursor(subscriptions[0])
+	languages := make([]string, 0)
+	contentRatings := make([]string, 0)
+	for _, sub := range subscriptions {
+		if c := cursor(sub); c.Before(since) {
+			since = c
+		}
+		for _, lang := range sub.GetLanguages() {
+			if !containsString(languages, lang) {
+				languages = append(languages, lang)
+			}
+		}
+		for _, rating := range sub.GetContentRatings(e.ContentRatings) {
+			if !containsString(contentRatings, rating) {
+				contentRatings = append(contentRatings, rating)
+			}
+		}
+	}
+
+	checkTime := time.Now()
+	chapters, err := e.apiClient.GetFollowedFeedContext(ctx, since, languages, contentRatings)
+	truncated := errors.Is(err, api.ErrChapterLimitReached)
+	if truncated {
+		// Chapters come oldest first, so resume after the last one read
+		checkTime = chapters[len(chapters)-1].CreatedAt
+	} else if err != nil {
+		return nil, err
+	}
+
+	byManga := make(map[string][]api.Chapter)
+	for _, chapter := range chapters {
+		byManga[chapter.MangaID] = append(byManga[chapter.MangaID], chapter)
+	}
+
+	results := make([]SubscriptionResult, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		// The feed was read from the oldest cursor; drop what this
+		// subscription has already covered
+		subCursor := cursor(sub)
+		subChapters := make([]api.Chapter, 0)
+		for _, chapter := range byManga[sub.MangaID] {
+			if !chapter.CreatedAt.Before(subCursor) {
+				subChapters = append(subChapters, chapter)
+			}
+		}
+
+		subResult := e.recordChapters(sub, subChapters, checkTime, truncated)
+		subResult.ViaFeed = true
+		results = append(results, subResult)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,71 @@
+package updater
+
+import (
+	"strings"
+
+	"mangadex-cli/internal/api"
+	"mangadex-cli/internal/db"
+)
+
+// filterByGroup applies a subscription's scanlation group preferences.
+// Chapters from a blocked group are dropped. When the same chapter is
+// released in the same language by a preferred group and by other groups,
+// only the preferred releases are kept.
+func filterByGroup(chapters []api.Chapter, sub db.Subscription) []api.Chapter {
+	blocked := sub.GetBlockedGroups()
+	preferred := sub.GetPreferredGroups()
+	if len(blocked) == 0 && len(preferred) == 0 {
+		return chapters
+	}
+
+	allowed := make([]api.Chapter, 0, len(chapters))
+	for _, chapter := range chapters {
+		if !hasGroup(chapter, blocked) {
+			allowed = append(allowed, chapter)
+		}
+	}
+
+	if len(preferred) == 0 {
+		return allowed
+	}
+
+	// Find the releases that have a preferred version
+	hasPreferred := make(map[string]bool)
+	for _, chapter := range allowed {
+		if hasGroup(chapter, preferred) {
+			hasPreferred[releaseKey(chapter)] = true
+		}
+	}
+
+	filtered := make([]api.Chapter, 0, len(allowed))
+	for _, chapter := range allowed {
+		if !hasPreferred[releaseKey(chapter)] || hasGroup(chapter, preferred) {
+			filtered = append(filtered, chapter)
+		}
+	}
+
+	return filtered
+}
+
+// hasGroup reports whether a chapter was released by any of the given
+// groups, matched by ID or case-insensitively by name
+func hasGroup(chapter api.Chapter, groups []string) bool {
+	names := chapter.GetGroupNames()
+	for i, id := range chapter.Groups {
+		for _, group := range groups {
+			if group == id || strings.EqualFold(group, names[i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// releaseKey identifies the same chapter released by different groups
+func releaseKey(chapter api.Chapter) string {
+	if chapter.Chapter == "" {
+		// Oneshots and volume-only releases have no number to match on
+		return chapter.ID
+	}
+	return chapter.TranslatedLanguage + "|" + chapter.Volume + "|" + chapter.Chapter
+}
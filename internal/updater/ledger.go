@@ -0,0 +1,47 @@
+package updater
+
+import (
+	"strings"
+
+	"mangadex-cli/internal/api"
+	"mangadex-cli/internal/db"
+)
+
+// toSeenChapter converts an API chapter into a ledger entry
+func toSeenChapter(mangaID string, chapter api.Chapter) db.SeenChapter {
+	return db.SeenChapter{
+		ChapterID:          chapter.ID,
+		MangaID:            mangaID,
+		Title:              chapter.Title,
+		Volume:             chapter.Volume,
+		Chapter:            chapter.Chapter,
+		TranslatedLanguage: chapter.TranslatedLanguage,
+		Groups:             strings.Join(chapter.Groups, ","),
+		GroupNames:         strings.Join(chapter.GroupNames, "\n"),
+		ReleaseKey:         releaseKey(chapter),
+		PublishAt:          chapter.PublishAt,
+		ChapterCreatedAt:   chapter.CreatedAt,
+	}
+}
+
+// toAPIChapter rebuilds an API chapter from a ledger entry
+func toAPIChapter(seen db.SeenChapter) api.Chapter {
+	groups := make([]string, 0)
+	names := make([]string, 0)
+	if seen.Groups != "" {
+		groups = strings.Split(seen.Groups, ",")
+		names = strings.Split(seen.GroupNames, "\n")
+	}
+
+	return api.Chapter{
+		ID:                 seen.ChapterID,
+		Title:              seen.Title,
+		Volume:             seen.Volume,
+		Chapter:            seen.Chapter,
+		TranslatedLanguage: seen.TranslatedLanguage,
+		Groups:             groups,
+		GroupNames:         names,
+		PublishAt:          seen.PublishAt,
+		CreatedAt:          seen.ChapterCreatedAt,
+	}
+}
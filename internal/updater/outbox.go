@@ -0,0 +1,413 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"mangadex-cli/internal/api"
+	"mangadex-cli/internal/db"
+	"mangadex-cli/internal/notify"
+)
+
+// Notifications are not sent directly. Each one is first queued as an outbox
+// item that references its chapters in the ledger, and a delivery pass sends
+// every item that is due. A failed item is retried with exponential backoff
+// until MaxDeliveryAttempts is reached, after which it is kept as failed until
+// it is retried or purged from the command line.
+
+// DefaultMaxDeliveryAttempts is used when the engine sets no attempt limit
+const DefaultMaxDeliveryAttempts = 8
+
+// Retry delays for failed outbox items double from outboxBaseDelay up to outboxMaxDelay
+const (
+	outboxBaseDelay = time.Minute
+	outboxMaxDelay  = 6 * time.Hour
+)
+
+// enqueueUser queues a user's updates on each of their channels, either
+// immediately or as a digest once one is due
+func (e *Engine) enqueueUser(result *Result, userID int, mangaUpdates []*mangaUpdate) {
+	// Get user
+	user, err := e.db.GetUser(userID)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("failed to get user with ID %d: %w", userID, err))
+		return
+	}
+
+	// Hold chapters in the ledger until the user's next digest
+	now := time.Now()
+	digest := user.GetDeliveryMode() != db.DeliveryImmediate
+	if digest {
+		if dueAt := nextDigest(user, now); dueAt.After(now) {
+			chapters := 0
+			for _, update := range mangaUpdates {
+				chapters += len(update.Chapters)
+			}
+			result.Deferred = append(result.Deferred, DeferredDigest{
+				UserID:   user.ID,
+				Email:    user.Email,
+				Chapters: chapters,
+				DueAt:    dueAt,
+			})
+			return
+		}
+	}
+
+	queued := result.Queued
+	for _, channel := range user.GetChannels() {
+		notifier, _ := e.notifiers.Get(channel)
+
+		// Digest-capable channels get one message for every series
+		if _, ok := notifier.(notify.DigestNotifier); ok && digest {
+			entries := make([]db.SeenChapter, 0)
+			titles := make([]string, 0)
+			for _, update := range mangaUpdates {
+				if pending := e.toQueue(result, user, channel, update); len(pending) > 0 {
+					entries = append(entries, pending...)
+					titles = append(titles, update.MangaTitle)
+				}
+			}
+			e.enqueue(result, user, channel, true, "", strings.Join(titles, ", "), entries)
+			continue
+		}
+
+		// Otherwise send one notification per manga
+		for _, update := range mangaUpdates {
+			e.enqueue(result, user, channel, digest, update.MangaID, update.MangaTitle, e.toQueue(result, user, channel, update))
+		}
+	}
+
+	// Failed digests are retried by the outbox, not held for the next slot
+	if digest && result.Queued > queued {
+		user.LastDigestAt = now
+		if err := e.db.UpdateUser(user); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to record digest time for %s: %w", user.Email, err))
+		}
+	}
+}
+
+// toQueue returns the ledger entries of an update still to be queued on a
+// channel. A channel shared by several users, such as a webhook, is sent each
+// chapter once: entries whose chapter another subscription already queued or
+// delivered there are marked delivered instead.
+func (e *Engine) toQueue(result *Result, user *db.User, channel string, update *mangaUpdate) []db.SeenChapter {
+	entries := update.unqueued(channel)
+	if notifier, ok := e.notifiers.Get(channel); !ok || !notify.Shared(notifier) || len(entries) == 0 {
+		return entries
+	}
+
+	chapterIDs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		chapterIDs = append(chapterIDs, entry.ChapterID)
+	}
+	posted, err := e.db.ChaptersOnChannel(channel, chapterIDs)
+	if err != nil {
+		// Posting twice is better than not at all
+		result.Errors = append(result.Errors, fmt.Errorf("failed to check %s for chapters already sent: %w", channel, err))
+		return entries
+	}
+
+	queue := make([]db.SeenChapter, 0, len(entries))
+	covered := make([]db.SeenChapter, 0)
+	for _, entry := range entries {
+		if posted[entry.ChapterID] {
+			covered = append(covered, entry)
+		} else {
+			queue = append(queue, entry)
+		}
+	}
+	if len(covered) > 0 {
+		if err := e.db.MarkChaptersDelivered(covered, channel, user.GetChannels()); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to mark chapters already sent to %s: %w", channel, err))
+		}
+	}
+	return queue
+}
+
+// enqueue adds an outbox item for ledger entries, if there are any
+func (e *Engine) enqueue(result *Result, user *db.User, channel string, digest bool, mangaID, summary string, entries []db.SeenChapter) {
+	if len(entries) == 0 {
+		return
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, strconv.Itoa(entry.ID))
+	}
+
+	item := &db.OutboxItem{
+		UserID:        user.ID,
+		Channel:       channel,
+		Digest:        digest,
+		MangaID:       mangaID,
+		Summary:       summary,
+		Chapters:      strings.Join(ids, ","),
+		Status:        db.OutboxPending,
+		NextAttemptAt: time.Now(),
+	}
+	if err := e.db.EnqueueOutboxItem(item); err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("failed to queue %s notification for %s: %w", channel, user.Email, err))
+		return
+	}
+	result.Queued++
+}
+
+// DeliverOutbox sends every outbox item that is due, without checking for new
+// chapters. It lets failed notifications be retried between update runs.
+func (e *Engine) DeliverOutbox() (*Result, error) {
+	return e.DeliverOutboxContext(context.Background())
+}
+
+// DeliverOutboxContext is like DeliverOutbox but can be cancelled through the
+// context
+func (e *Engine) DeliverOutboxContext(ctx context.Context) (*Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	result := &Result{StartedAt: time.Now()}
+	defer func() { result.FinishedAt = time.Now() }()
+
+	if err := e.deliverOutbox(ctx, result, time.Now()); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// deliverOutbox attempts every outbox item due at now, stopping early if the
+// context is cancelled
+func (e *Engine) deliverOutbox(ctx context.Context, result *Result, now time.Time) error {
+	items, err := e.db.ListDueOutboxItems(now)
+	if err != nil {
+		return fmt.Errorf("failed to get outbox: %w", err)
+	}
+
+	// Manga details are shared by every item of this pass
+	mangas := make(map[string]*api.Manga)
+	for i := range items {
+		if err := ctx.Err(); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("outbox delivery stopped with %d item(s) left: %w", len(items)-i, err))
+			break
+		}
+		result.Notifications = append(result.Notifications, e.deliverItem(ctx, &items[i], mangas))
+	}
+
+	// Connections are reused within a pass, not kept open between passes
+	if err := e.notifiers.Close(); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+
+	return nil
+}
+
+// deliverItem makes one delivery attempt of an outbox item and records the
+// outcome. Its chapters are only marked delivered once the notifier confirms it.
+func (e *Engine) deliverItem(ctx context.Context, item *db.OutboxItem, mangas map[string]*api.Manga) NotificationResult {
+	notification := NotificationResult{
+		OutboxID:   item.ID,
+		UserID:     item.UserID,
+		Channel:    item.Channel,
+		MangaID:    item.MangaID,
+		MangaTitle: item.Summary,
+		Digest:     item.Digest,
+		Attempt:    item.Attempts + 1,
+	}
+
+	user, err := e.db.GetUser(item.UserID)
+	if err != nil {
+		notification.Err = fmt.Errorf("failed to get user with ID %d: %w", item.UserID, err)
+		e.recordFailure(item, &notification)
+		return notification
+	}
+	notification.Email = user.Email
+
+	// Users who unsubscribed after the item was queued are not sent it
+	if !user.Active {
+		e.dropItem(item, &notification, fmt.Errorf("%s has unsubscribed", user.Email))
+		return notification
+	}
+
+	entries, err := e.db.GetSeenChapters(item.GetChapterIDs())
+	if err == nil {
+		entries, err = e.subscribedEntries(entries)
+	}
+	if err != nil {
+		notification.Err = fmt.Errorf("failed to get queued chapters: %w", err)
+		e.recordFailure(item, &notification)
+		return notification
+	}
+
+	// Nor are chapters of subscriptions ended or deleted since, e.g. by an
+	// unsubscribe link or a follows sync
+	if len(entries) == 0 {
+		e.dropItem(item, &notification, fmt.Errorf("%s is no longer subscribed to %s", user.Email, item.Summary))
+		return notification
+	}
+
+	// Rebuild the series and their chapters from the ledger
+	updates, err := e.outboxUpdates(ctx, entries, mangas)
+	for _, update := range updates {
+		notification.Chapters = append(notification.Chapters, update.Chapters...)
+	}
+	if err == nil {
+		err = e.send(ctx, user, item, updates)
+	}
+	if err != nil {
+		notification.Err = err
+		// An interrupted attempt does not count; the item stays due
+		if ctx.Err() != nil {
+			notification.Interrupted = true
+		} else {
+			e.recordFailure(item, &notification)
+		}
+		return notification
+	}
+
+	if err := e.db.CompleteOutboxItem(item, user.GetChannels()); err != nil {
+		notification.Err = fmt.Errorf("notification sent but failed to update outbox: %w", err)
+	}
+	return notification
+}
+
+// subscribedEntries keeps the ledger entries whose subscription still exists
+// and is active
+func (e *Engine) subscribedEntries(entries []db.SeenChapter) ([]db.SeenChapter, error) {
+	ids := make([]int, 0)
+	seen := make(map[int]bool)
+	for _, entry := range entries {
+		if !seen[entry.SubscriptionID] {
+			seen[entry.SubscriptionID] = true
+			ids = append(ids, entry.SubscriptionID)
+		}
+	}
+
+	subscriptions, err := e.db.GetSubscriptionsByID(ids)
+	if err != nil {
+		return nil, err
+	}
+	active := make(map[int]bool, len(subscriptions))
+	for _, sub := range subscriptions {
+		active[sub.ID] = sub.Active
+	}
+
+	kept := make([]db.SeenChapter, 0, len(entries))
+	for _, entry := range entries {
+		if active[entry.SubscriptionID] {
+			kept = append(kept, entry)
+		}
+	}
+	return kept, nil
+}
+
+// outboxUpdates groups ledger entries by manga, in first-seen order, with the
+// manga details needed to render them
+func (e *Engine) outboxUpdates(ctx context.Context, entries []db.SeenChapter, mangas map[string]*api.Manga) ([]notify.Update, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("queued chapters no longer exist")
+	}
+
+	updates := make([]notify.Update, 0)
+	index := make(map[string]int)
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		// The same chapter can be queued for several subscriptions
+		if seen[entry.MangaID+"/"+entry.ChapterID] {
+			continue
+		}
+		seen[entry.MangaID+"/"+entry.ChapterID] = true
+
+		i, ok := index[entry.MangaID]
+		if !ok {
+			manga, cached := mangas[entry.MangaID]
+			if !cached {
+				var err error
+				manga, err = e.apiClient.GetMangaContext(ctx, entry.MangaID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get manga details for %s: %w", entry.MangaID, err)
+				}
+				mangas[entry.MangaID] = manga
+			}
+
+			i = len(updates)
+			index[entry.MangaID] = i
+			updates = append(updates, notify.Update{Manga: manga})
+		}
+		updates[i].Chapters = append(updates[i].Chapters, toAPIChapter(entry))
+	}
+
+	for _, update := range updates {
+		api.SortChapters(update.Chapters)
+	}
+	return updates, nil
+}
+
+// send delivers an outbox item's updates through its channel's notifier
+func (e *Engine) send(ctx context.Context, user *db.User, item *db.OutboxItem, updates []notify.Update) error {
+	notifier, ok := e.notifiers.Get(item.Channel)
+	if !ok {
+		return fmt.Errorf("unknown notification channel %q", item.Channel)
+	}
+
+	if digester, ok := notifier.(notify.DigestNotifier); ok && item.Digest && item.MangaID == "" {
+		if err := digester.NotifyDigest(ctx, user, updates); err != nil {
+			return fmt.Errorf("failed to send %s digest to %s: %w", item.Channel, user.Email, err)
+		}
+		return nil
+	}
+
+	for _, update := range updates {
+		if err := notifier.Notify(ctx, user, update.Manga, update.Chapters); err != nil {
+			return fmt.Errorf("failed to send %s notification to %s: %w", item.Channel, user.Email, err)
+		}
+	}
+	return nil
+}
+
+// dropItem marks an outbox item failed without further attempts, as it
+// should no longer be sent
+func (e *Engine) dropItem(item *db.OutboxItem, notification *NotificationResult, reason error) {
+	notification.Err = reason
+	item.Attempts++
+	item.Status = db.OutboxFailed
+	item.LastError = reason.Error()
+	if err := e.db.UpdateOutboxItem(item); err != nil {
+		notification.Err = fmt.Errorf("%v (and failed to update outbox: %w)", notification.Err, err)
+	}
+}
+
+// recordFailure schedules the next attempt of a failed outbox item, or marks
+// it failed once it has used up its attempts
+func (e *Engine) recordFailure(item *db.OutboxItem, notification *NotificationResult) {
+	item.Attempts++
+	item.LastError = notification.Err.Error()
+
+	maxAttempts := e.MaxDeliveryAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxDeliveryAttempts
+	}
+
+	if item.Attempts >= maxAttempts {
+		item.Status = db.OutboxFailed
+	} else {
+		item.NextAttemptAt = time.Now().Add(outboxBackoff(item.Attempts))
+		notification.RetryAt = item.NextAttemptAt
+	}
+
+	if err := e.db.UpdateOutboxItem(item); err != nil {
+		notification.Err = fmt.Errorf("%v (and failed to update outbox: %w)", notification.Err, err)
+	}
+}
+
+// outboxBackoff returns the delay before the next attempt after a number of failures
+func outboxBackoff(attempts int) time.Duration {
+	delay := outboxBaseDelay
+	for i := 1; i < attempts && delay < outboxMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > outboxMaxDelay {
+		delay = outboxMaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,131 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"mangadex-cli/internal/api"
+	"mangadex-cli/internal/db"
+)
+
+// DefaultWorkers is how many manga are polled at once when the engine sets
+// no worker count
+const DefaultWorkers = 4
+
+// mangaFetch is the outcome of polling one manga for its subscriptions
+type mangaFetch struct {
+	chapters  []api.Chapter
+	checkTime time.Time
+	truncated bool
+	err       error
+}
+
+// pollSubscriptions checks subscriptions by polling their manga. Subscriptions
+// to the same manga share a single fetch, and fetches run on up to Workers
+// goroutines sharing the client's rate limiter. Chapters are recorded in the
+// ledger afterwards on the calling goroutine, in subscription order, as SQLite
+// allows only one writer at a time.
+func (e *Engine) pollSubscriptions(ctx context.Context, subscriptions []db.Subscription) []SubscriptionResult {
+	groups := make(map[string][]db.Subscription)
+	mangaIDs := make([]string, 0)
+	for _, sub := range subscriptions {
+		if _, ok := groups[sub.MangaID]; !ok {
+			mangaIDs = append(mangaIDs, sub.MangaID)
+		}
+		groups[sub.MangaID] = append(groups[sub.MangaID], sub)
+	}
+
+	workers := e.Workers
+	if workers < 1 {
+		workers = DefaultWorkers
+	}
+	if workers > len(mangaIDs) {
+		workers = len(mangaIDs)
+	}
+
+	fetches := make([]mangaFetch, len(mangaIDs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fetches[i] = e.fetchChapters(ctx, groups[mangaIDs[i]])
+			}
+		}()
+	}
+	for i := range mangaIDs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	byManga := make(map[string]*mangaFetch, len(mangaIDs))
+	for i, mangaID := range mangaIDs {
+		byManga[mangaID] = &fetches[i]
+	}
+
+	results := make([]SubscriptionResult, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		fetch := byManga[sub.MangaID]
+		if fetch.err != nil {
+			results = append(results, SubscriptionResult{
+				Subscription: sub,
+				Err:          fmt.Errorf("failed to get chapters for \"%s\": %w", sub.MangaTitle, fetch.err),
+			})
+			continue
+		}
+
+		// The manga was polled from the oldest cursor; drop what this
+		// subscription has already covered
+		subCursor := cursor(sub)
+		subChapters := make([]api.Chapter, 0, len(fetch.chapters))
+		for _, chapter := range fetch.chapters {
+			if !chapter.CreatedAt.Before(subCursor) {
+				subChapters = append(subChapters, chapter)
+			}
+		}
+
+		results = append(results, e.recordChapters(sub, subChapters, fetch.checkTime, fetch.truncated))
+	}
+
+	return results
+}
+
+// fetchChapters polls a manga for new chapters once, from the oldest cursor
+// and across the languages and content ratings of all its subscriptions.
+// Each subscription's own filters apply when the chapters are recorded.
+func (e *Engine) fetchChapters(ctx context.Context, subscriptions []db.Subscription) mangaFetch {
+	since := cursor(subscriptions[0])
+	languages := make([]string, 0)
+	contentRatings := make([]string, 0)
+	for _, sub := range subscriptions {
+		if c := cursor(sub); c.Before(since) {
+			since = c
+		}
+		for _, lang := range sub.GetLanguages() {
+			if !containsString(languages, lang) {
+				languages = append(languages, lang)
+			}
+		}
+		for _, rating := range sub.GetContentRatings(e.ContentRatings) {
+			if !containsString(contentRatings, rating) {
+				contentRatings = append(contentRatings, rating)
+			}
+		}
+	}
+
+	fetch := mangaFetch{checkTime: time.Now()}
+	fetch.chapters, fetch.err = e.apiClient.GetMangaChaptersContext(ctx, subscriptions[0].MangaID, since, languages, contentRatings)
+	if errors.Is(fetch.err, api.ErrChapterLimitReached) {
+		// Chapters come oldest first, so resume after the last one read
+		fetch.checkTime = fetch.chapters[len(fetch.chapters)-1].CreatedAt
+		fetch.truncated = true
+		fetch.err = nil
+	}
+	return fetch
+}
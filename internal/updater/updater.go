@@ -0,0 +1,395 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"mangadex-cli/internal/api"
+	"mangadex-cli/internal/db"
+	"mangadex-cli/internal/notify"
+)
+
+// cursorOverlap is subtracted from a subscription's last check time when
+// querying for new chapters, so clock skew between us and MangaDex cannot hide
+// chapters. Chapters seen twice are deduplicated by the chapter ledger.
+const cursorOverlap = 10 * time.Minute
+
+// Engine checks subscriptions for new chapters and sends notifications.
+// It is shared by the check command and the scheduled service.
+type Engine struct {
+	// FeedMode pulls chapters for followed manga from the logged-in user's
+	// follow feed instead of polling each subscription separately
+	FeedMode bool
+
+	// ContentRatings applies to subscriptions that do not set their own
+	ContentRatings []string
+
+	// MaxDeliveryAttempts is how often an outbox item is tried before it is
+	// marked failed
+	MaxDeliveryAttempts int
+
+	// Workers is how many manga are polled for new chapters at once
+	Workers int
+
+	db        *db.DB
+	apiClient *api.MangaDexClient
+	notifiers *notify.Registry
+	mu        sync.Mutex // Serializes runs and outbox deliveries
+}
+
+// NewEngine creates a new update engine
+func NewEngine(database *db.DB, client *api.MangaDexClient, notifiers *notify.Registry) *Engine {
+	return &Engine{
+		ContentRatings:      api.DefaultContentRatings,
+		MaxDeliveryAttempts: DefaultMaxDeliveryAttempts,
+		Workers:             DefaultWorkers,
+		db:                  database,
+		apiClient:           client,
+		notifiers:           notifiers,
+	}
+}
+
+// SubscriptionResult is the outcome of checking a single subscription
+type SubscriptionResult struct {
+	Subscription db.Subscription
+	Chapters     []api.Chapter // Chapters matching the subscription that were recorded in the ledger by this run
+	Truncated    bool          // More chapters are waiting than the client's limit allowed; the rest follow next run
+	ViaFeed      bool          // Chapters came from the follow feed rather than per-manga polling
+	Held         int           // Chapters waiting for other groups' releases under the wait dedup policy
+	Err          error
+
+	pending []db.SeenChapter // Undelivered ledger entries, including chapters already queued on some channels
+}
+
+// NotificationResult is the outcome of one delivery attempt of an outbox item:
+// one manga, or a digest of several, for one user on one channel
+type NotificationResult struct {
+	OutboxID    int
+	UserID      int
+	Email       string
+	Channel     string
+	MangaID     string // Empty for digests of several series
+	MangaTitle  string // Manga title, or the series of a digest
+	Chapters    []api.Chapter
+	Digest      bool // Delivered as part of a digest
+	Err         error
+	Attempt     int       // Delivery attempt, starting at 1
+	RetryAt     time.Time // Next attempt after a failure; zero once the item has failed for good
+	Interrupted bool      // Cut short by cancellation; the attempt does not count and the item stays due
+}
+
+// DeferredDigest reports a user whose chapters are held for a later digest
+type DeferredDigest struct {
+	UserID   int
+	Email    string
+	Chapters int
+	DueAt    time.Time
+}
+
+// Sent reports whether the notification was delivered
+func (n *NotificationResult) Sent() bool {
+	return n.Err == nil
+}
+
+// Result summarizes a single update run
+type Result struct {
+	StartedAt     time.Time
+	FinishedAt    time.Time
+	Subscriptions []SubscriptionResult
+	Notifications []NotificationResult
+	Deferred      []DeferredDigest
+	Queued        int     // Notifications added to the outbox
+	APICalls      int     // Requests sent to MangaDex to check for new chapters
+	APICallsSaved int     // Subscriptions checked minus chapter fetches, i.e. fetches saved by sharing one per manga or the follow feed; retries and extra pages are not counted
+	Errors        []error // Errors not tied to a single subscription or notification
+}
+
+// ChaptersFound returns the total number of new chapters across all
+// subscriptions. Chapters found by earlier runs and still held, e.g. for a
+// digest, are not counted again.
+func (r *Result) ChaptersFound() int {
+	total := 0
+	for _, sub := range r.Subscriptions {
+		total += len(sub.Chapters)
+	}
+	return total
+}
+
+// NotificationsSent returns the number of notifications delivered successfully
+func (r *Result) NotificationsSent() int {
+	sent := 0
+	for _, n := range r.Notifications {
+		if n.Sent() {
+			sent++
+		}
+	}
+	return sent
+}
+
+// ErrorCount returns the number of errors encountered during the run
+func (r *Result) ErrorCount() int {
+	count := len(r.Errors)
+	for _, sub := range r.Subscriptions {
+		if sub.Err != nil {
+			count++
+		}
+	}
+	for _, n := range r.Notifications {
+		if n.Err != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// mangaUpdate collects the new chapters of one manga for one user
+type mangaUpdate struct {
+	MangaID    string
+	MangaTitle string
+	Chapters   []api.Chapter
+	entries    map[string][]db.SeenChapter // Chapter ID -> ledger entries across the user's subscriptions
+}
+
+// add merges a subscription's pending chapters into the update, skipping duplicates
+func (u *mangaUpdate) add(pending []db.SeenChapter) {
+	for _, entry := range pending {
+		if _, ok := u.entries[entry.ChapterID]; !ok {
+			u.Chapters = append(u.Chapters, toAPIChapter(entry))
+		}
+		u.entries[entry.ChapterID] = append(u.entries[entry.ChapterID], entry)
+	}
+	api.SortChapters(u.Chapters)
+}
+
+// unqueued returns the ledger entries of chapters that are neither delivered
+// nor queued on a channel, in chapter order
+func (u *mangaUpdate) unqueued(channel string) []db.SeenChapter {
+	entries := make([]db.SeenChapter, 0)
+	for _, chapter := range u.Chapters {
+		for _, entry := range u.entries[chapter.ID] {
+			if !entry.IsDelivered(channel) && !entry.IsQueued(channel) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries
+}
+
+// Run checks all active subscriptions for new chapters, queues notifications
+// for them in the outbox and delivers every outbox item that is due.
+// An error is only returned if the run could not be performed at all; per
+// subscription and per notification failures are recorded in the result.
+func (e *Engine) Run() (*Result, error) {
+	return e.RunContext(context.Background())
+}
+
+// RunContext is like Run but can be cancelled through the context. Requests
+// in flight are abandoned, and outbox items not yet sent stay due for the
+// next run.
+func (e *Engine) RunContext(ctx context.Context) (*Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	result := &Result{StartedAt: time.Now()}
+	defer func() { result.FinishedAt = time.Now() }()
+
+	// Get active subscriptions
+	subscriptions, err := e.db.ListActiveSubscriptions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscriptions: %w", err)
+	}
+
+	// Followed manga come from the feed, everything else is polled
+	requestsBefore := e.apiClient.RequestCount()
+	fetches := 0
+	polled := subscriptions
+	if e.FeedMode && e.apiClient.IsAuthenticated() {
+		var feedSubs []db.Subscription
+		feedSubs, polled, err = e.splitByFollows(ctx, subscriptions)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to get followed manga, polling every subscription: %w", err))
+			polled = subscriptions
+		} else if len(feedSubs) > 0 {
+			fetches++
+			feedResults, err := e.checkFeed(ctx, feedSubs)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Errorf("failed to read follow feed, polling every subscription: %w", err))
+				polled = append(polled, feedSubs...)
+			} else {
+				result.Subscriptions = append(result.Subscriptions, feedResults...)
+			}
+		}
+	}
+
+	if len(polled) > 0 {
+		result.Subscriptions = append(result.Subscriptions, e.pollSubscriptions(ctx, polled)...)
+		fetches += countManga(polled)
+	}
+
+	// Each polled manga is fetched once however many subscriptions it has,
+	// and the feed once for every followed manga
+	result.APICalls = int(e.apiClient.RequestCount() - requestsBefore)
+	result.APICallsSaved = len(subscriptions) - fetches
+
+	// Track new chapters by user and manga, keeping first-seen order
+	updates := make(map[int]map[string]*mangaUpdate) // UserID -> MangaID -> update
+	userOrder := make([]int, 0)
+	mangaOrder := make(map[int][]string)
+
+	for _, subResult := range result.Subscriptions {
+		if subResult.Err != nil || len(subResult.pending) == 0 {
+			continue
+		}
+		sub := subResult.Subscription
+
+		// Group updates by user and manga
+		if _, ok := updates[sub.UserID]; !ok {
+			updates[sub.UserID] = make(map[string]*mangaUpdate)
+			userOrder = append(userOrder, sub.UserID)
+		}
+
+		update, ok := updates[sub.UserID][sub.MangaID]
+		if !ok {
+			update = &mangaUpdate{
+				MangaID:    sub.MangaID,
+				MangaTitle: sub.MangaTitle,
+				entries:    make(map[string][]db.SeenChapter),
+			}
+			updates[sub.UserID][sub.MangaID] = update
+			mangaOrder[sub.UserID] = append(mangaOrder[sub.UserID], sub.MangaID)
+		}
+		update.add(subResult.pending)
+	}
+
+	// Queue notifications for each user
+	for _, userID := range userOrder {
+		mangaUpdates := make([]*mangaUpdate, 0, len(mangaOrder[userID]))
+		for _, mangaID := range mangaOrder[userID] {
+			mangaUpdates = append(mangaUpdates, updates[userID][mangaID])
+		}
+
+		e.enqueueUser(result, userID, mangaUpdates)
+	}
+
+	// Deliver everything due, including retries of earlier failures
+	if err := e.deliverOutbox(ctx, result, time.Now()); err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+
+	return result, nil
+}
+
+// countManga returns the number of distinct manga among subscriptions
+func countManga(subscriptions []db.Subscription) int {
+	mangaIDs := make(map[string]bool)
+	for _, sub := range subscriptions {
+		mangaIDs[sub.MangaID] = true
+	}
+	return len(mangaIDs)
+}
+
+// cursor returns the time to query a subscription's new chapters from, with
+// some overlap for clock skew. After a check that hit the chapter limit, it
+// resumes exactly from the last chapter read, as more chapters than the limit
+// can fall within the overlap and the check would never get past them.
+func cursor(sub db.Subscription) time.Time {
+	if sub.LastCheckTime.IsZero() || sub.CheckTruncated {
+		return sub.LastCheckTime
+	}
+	return sub.LastCheckTime.Add(-cursorOverlap)
+}
+
+// recordChapters records a subscription's fetched chapters in the chapter
+// ledger and returns every chapter that still needs to be notified. truncated
+// marks a fetch that hit the chapter limit and read up to checkTime only.
+func (e *Engine) recordChapters(sub db.Subscription, chapters []api.Chapter, checkTime time.Time, truncated bool) SubscriptionResult {
+	subResult := SubscriptionResult{Subscription: sub, Truncated: truncated}
+
+	chapters = filterByLanguage(chapters, sub.GetLanguages())
+	chapters = filterByContentRating(chapters, sub.GetContentRatings(e.ContentRatings))
+	chapters = filterByGroup(chapters, sub)
+
+	// Record chapters and advance the check time together, so a crash can
+	// never move the cursor past chapters that are not in the ledger
+	seen := make([]db.SeenChapter, 0, len(chapters))
+	for _, chapter := range chapters {
+		seen = append(seen, toSeenChapter(sub.MangaID, chapter))
+	}
+
+	// A truncated fetch shared with other subscriptions can end before this
+	// subscription's own cursor, which must never move backwards
+	if checkTime.After(sub.LastCheckTime) {
+		sub.LastCheckTime = checkTime
+		sub.CheckTruncated = truncated
+	}
+	added, err := e.db.RecordSeenChapters(&sub, seen)
+	if err != nil {
+		subResult.Err = fmt.Errorf("failed to record chapters for \"%s\": %w", sub.MangaTitle, err)
+		return subResult
+	}
+	subResult.Subscription = sub
+
+	// Notify about everything not yet delivered, including earlier failures
+	pending, err := e.db.ListPendingChapters(sub.ID)
+	if err != nil {
+		subResult.Err = fmt.Errorf("failed to get pending chapters for \"%s\": %w", sub.MangaTitle, err)
+		return subResult
+	}
+
+	// Collapse duplicate releases of the same chapter
+	pending, subResult.Held, err = e.dedupReleases(sub, pending, time.Now())
+	if err != nil {
+		subResult.Err = fmt.Errorf("failed to deduplicate chapters for \"%s\": %w", sub.MangaTitle, err)
+		return subResult
+	}
+
+	subResult.pending = pending
+	for _, chapter := range added {
+		subResult.Chapters = append(subResult.Chapters, toAPIChapter(chapter))
+	}
+
+	return subResult
+}
+
+// filterByContentRating keeps only chapters of manga with one of the given
+// content ratings. Chapters without a known rating were already filtered by
+// the query and are kept.
+func filterByContentRating(chapters []api.Chapter, contentRatings []string) []api.Chapter {
+	if len(contentRatings) == 0 {
+		return chapters
+	}
+
+	filtered := make([]api.Chapter, 0, len(chapters))
+	for _, chapter := range chapters {
+		if chapter.ContentRating == "" || containsString(contentRatings, chapter.ContentRating) {
+			filtered = append(filtered, chapter)
+		}
+	}
+	return filtered
+}
+
+// containsString reports whether a list contains a value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// filterByLanguage keeps only chapters translated into one of the given languages
+func filterByLanguage(chapters []api.Chapter, languages []string) []api.Chapter {
+	filtered := make([]api.Chapter, 0)
+	for _, chapter := range chapters {
+		for _, lang := range languages {
+			if chapter.TranslatedLanguage == lang {
+				filtered = append(filtered, chapter)
+				break
+			}
+		}
+	}
+	return filtered
+}